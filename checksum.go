@@ -0,0 +1,54 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package unsafeslice
+
+import "sync"
+
+// ChecksumString returns a checksum of the contents of s, computed with the
+// same seeded hash that mutation checks use by default.
+//
+// The seed is chosen once per process, so checksums are not stable across runs
+// and must not be persisted or sent to other processes.
+func ChecksumString(s string) uint64 {
+	return checksum(stringBytes(s))
+}
+
+// checksum returns the checksum of b using the default hash.
+func checksum(b []byte) uint64 {
+	h := newHash()
+	initHash(h)
+
+	h.Write(b)
+	sum := h.Sum64()
+
+	disposeHash(h)
+	return sum
+}
+
+// InternString returns a string with the same contents as b. Calls with equal
+// contents return strings that share a single backing array, so interning
+// many identical keys retains only one copy of their bytes.
+//
+// The first call for a given content copies b; later calls look it up by
+// checksum and do not allocate. In the unlikely event that two different
+// contents have the same checksum, only the first is interned, and
+// InternString returns a fresh copy of the other.
+//
+// Interned strings are never released, so InternString should be used only
+// for sets of keys that are bounded in size.
+func InternString(b []byte) string {
+	sum := checksum(b)
+	v, ok := interned.Load(sum)
+	if !ok {
+		v, _ = interned.LoadOrStore(sum, string(b))
+	}
+	if s := v.(string); s == string(b) {
+		return s
+	}
+	return string(b)
+}
+
+// interned maps the checksum of each interned string to the string itself.
+var interned sync.Map
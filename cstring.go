@@ -0,0 +1,250 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package unsafeslice
+
+import (
+	"bytes"
+	"fmt"
+	"sync/atomic"
+	"unicode/utf16"
+	"unsafe"
+)
+
+// This file contains functions for viewing NUL-terminated C strings,
+// including the UTF-16 “wide” strings used by Windows APIs.
+
+// CChar is a constraint matching the types that cgo may use for C.char.
+type CChar interface {
+	~int8 | ~uint8
+}
+
+// OfCStringN returns a byte slice that refers to the NUL-terminated C string at
+// p, examining at most max elements.
+//
+// If a NUL element is found among the first max elements, OfCStringN returns
+// the elements before it and true. Otherwise, it returns the first max
+// elements and false. OfCStringN never reads past p+max, so it is safe to use
+// on a fixed-size buffer that may not contain a terminator. If p is nil,
+// OfCStringN returns nil and false.
+func OfCStringN[T CChar](p *T, max int) ([]byte, bool) {
+	if p == nil {
+		return nil, false
+	}
+	n, ok := strLenN("OfCStringN", p, max)
+	return SliceAt[byte](unsafe.Pointer(p), n), ok
+}
+
+// OfCStringIn returns the NUL-terminated C string that begins at buf[start],
+// as a subslice of buf.
+//
+// If buf contains a NUL byte at or after start, OfCStringIn returns the bytes
+// before it and true. Otherwise, it returns buf[start:] and false. OfCStringIn
+// never reads outside of buf, so it is the safe counterpart to OfCStringSpan
+// when the enclosing buffer is known. It panics unless
+// 0 <= start <= len(buf).
+func OfCStringIn(buf []byte, start int) ([]byte, bool) {
+	if start < 0 || start > len(buf) {
+		panic(fmt.Sprintf("OfCStringIn: start %d out of range for buffer of length %d", start, len(buf)))
+	}
+	s := buf[start:]
+	if i := bytes.IndexByte(s, 0); i >= 0 {
+		return s[:i], true
+	}
+	return s, false
+}
+
+// StrLenN returns the number of elements at p before the first zero element,
+// examining at most max elements.
+//
+// If a zero element is found among the first max elements, StrLenN returns its
+// index and true. Otherwise, it returns max and false. If p is nil, StrLenN
+// returns 0 and false.
+func StrLenN[T comparable](p *T, max int) (n int, terminated bool) {
+	return strLenN("StrLenN", p, max)
+}
+
+// OfCStringSpan returns a byte slice that refers to the NUL-terminated C
+// string at p, along with the total number of elements it occupies including
+// the terminator. total is the distance to advance p to reach the element
+// following the string. If p is nil, OfCStringSpan returns nil and 0.
+//
+// The caller must ensure that p points to a NUL-terminated string, and that
+// its memory remains valid for as long as the returned slice is in use.
+func OfCStringSpan[T CChar](p *T) (data []byte, total int) {
+	s, total := cStringSpan("OfCStringSpan", p)
+	return ConvertTo[byte](s), total
+}
+
+// Int8ToBytes returns a byte slice that refers to the NUL-terminated C string
+// at p, which is the type of *C.char on platforms where char is signed. If p
+// is nil, Int8ToBytes returns nil.
+//
+// Int8ToBytes is equivalent to the first result of OfCStringSpan(p), for
+// callers that prefer not to instantiate a generic function. The same
+// requirements apply as for OfCStringSpan.
+func Int8ToBytes(p *int8) []byte {
+	data, _ := OfCStringSpan(p)
+	return data
+}
+
+// Uint8ToBytes is like Int8ToBytes, but for platforms on which C.char is
+// unsigned.
+func Uint8ToBytes(p *uint8) []byte {
+	data, _ := OfCStringSpan(p)
+	return data
+}
+
+// CStringsUntilDoubleNull splits a block of consecutive NUL-terminated strings,
+// ending with an empty string (that is, a double NUL), into slices that refer
+// to each string in the block. This is the layout of environment blocks and
+// similar data returned by some native APIs, including the UTF-16 block
+// returned by GetEnvironmentStringsW, for which T is uint16. If p is nil or the
+// block is empty, CStringsUntilDoubleNull returns nil.
+func CStringsUntilDoubleNull[T CChar | ~uint16](p *T) [][]T {
+	var ss [][]T
+	for p != nil {
+		s, total := cStringSpan("CStringsUntilDoubleNull", p)
+		if len(s) == 0 {
+			break
+		}
+		ss = append(ss, s)
+		p = (*T)(unsafe.Add(unsafe.Pointer(p), uintptr(total)*unsafe.Sizeof(*p)))
+	}
+	return ss
+}
+
+// cStringSpan returns a slice that refers to the elements at p before the first
+// zero element, along with the total number of elements it occupies including
+// the terminator, using op as the name of the operation in panics. If p is nil,
+// cStringSpan returns nil and 0.
+func cStringSpan[T comparable](op string, p *T) (data []T, total int) {
+	if p == nil {
+		return nil, 0
+	}
+	n := strLen(op, p)
+	return SliceAt[T](unsafe.Pointer(p), n), n + 1
+}
+
+// GoString returns a copy of the NUL-terminated C string at p, like C.GoString.
+// If p is nil, GoString returns the empty string.
+//
+// Unlike OfCStringN, the result does not alias the memory at p, so it remains
+// valid after that memory is freed.
+func GoString[T CChar](p *T) string {
+	return string(SliceAt[byte](unsafe.Pointer(p), strLen("GoString", p)))
+}
+
+// GoStringN returns a copy of the n elements at p as a string, like
+// C.GoStringN. Any NUL elements among them are included in the result. If p is
+// nil, GoStringN returns the empty string.
+func GoStringN[T CChar](p *T, n int) string {
+	if n < 0 {
+		panic(fmt.Sprintf("GoStringN with negative length %d", n))
+	}
+	if p == nil {
+		return ""
+	}
+	return string(SliceAt[byte](unsafe.Pointer(p), n))
+}
+
+// strLenN implements StrLenN, using op as the name of the operation in panics.
+func strLenN[T comparable](op string, p *T, max int) (int, bool) {
+	if max < 0 {
+		panic(fmt.Sprintf("%s with negative max %d", op, max))
+	}
+	if p == nil {
+		return 0, false
+	}
+
+	var zero T
+	size := unsafe.Sizeof(zero)
+	for i := 0; i < max; i++ {
+		if *(*T)(unsafe.Add(unsafe.Pointer(p), uintptr(i)*size)) == zero {
+			return i, true
+		}
+	}
+	return max, false
+}
+
+// SetStrLenLimit sets the maximum number of elements that functions which scan
+// for a terminator without an explicit bound, such as GoString, OfCStringSpan,
+// WStrLen, and TypedCString, examine before giving up. If they find no
+// terminator within the limit they panic, rather than continuing to read
+// memory that is likely past the end of an unterminated string.
+//
+// A limit of zero, the default, removes the limit. SetStrLenLimit panics if n
+// is negative. Callers that need to handle unterminated input without
+// panicking should use StrLenN or OfCStringN instead.
+func SetStrLenLimit(n int) {
+	if n < 0 {
+		panic(fmt.Sprintf("SetStrLenLimit with negative limit %d", n))
+	}
+	atomic.StoreInt64(&strLenLimit, int64(n))
+}
+
+// strLenLimit is the limit most recently set by SetStrLenLimit.
+var strLenLimit int64
+
+// strLen returns the number of elements at p before the first zero element,
+// using op as the name of the operation in panics. If p is nil, strLen
+// returns 0.
+func strLen[T comparable](op string, p *T) int {
+	if p == nil {
+		return 0
+	}
+
+	limit := int(atomic.LoadInt64(&strLenLimit))
+	var zero T
+	size := unsafe.Sizeof(zero)
+	n := 0
+	for *(*T)(unsafe.Add(unsafe.Pointer(p), uintptr(n)*size)) != zero {
+		n++
+		if n < 0 {
+			panic(op + ": length overflow")
+		}
+		if n == limit {
+			panic(fmt.Sprintf("%s: exceeded limit %d, string likely not terminated", op, limit))
+		}
+	}
+	return n
+}
+
+// WStrLen returns the number of UTF-16 code units in the NUL-terminated wide
+// string at p, not including the terminator. If p is nil, WStrLen returns 0.
+func WStrLen(p *uint16) int {
+	return strLen("WStrLen", p)
+}
+
+// OfWString returns a slice that refers to the UTF-16 code units of the
+// NUL-terminated wide string at p, not including the terminator. If p is nil,
+// OfWString returns nil.
+//
+// The caller must ensure that the memory at p remains valid for as long as the
+// returned slice is in use.
+func OfWString(p *uint16) []uint16 {
+	return SliceAt[uint16](unsafe.Pointer(p), strLen("OfWString", p))
+}
+
+// TypedCString returns a slice that refers to the elements at p before the
+// first zero element, for arrays of non-byte C types that are terminated by a
+// zero value, such as a list of int32 values ending in 0. If p is nil,
+// TypedCString returns nil.
+//
+// The caller must ensure that the array at p is terminated, and that its memory
+// remains valid for as long as the returned slice is in use. OfWString is the
+// special case of TypedCString for uint16.
+func TypedCString[T comparable](p *T) []T {
+	return SliceAt[T](unsafe.Pointer(p), strLen("TypedCString", p))
+}
+
+// WStringToString returns a copy of the NUL-terminated wide string at p,
+// decoded from UTF-16 to UTF-8. Invalid surrogate pairs are replaced by
+// U+FFFD. If p is nil, WStringToString returns the empty string.
+func WStringToString(p *uint16) string {
+	return string(utf16.Decode(OfWString(p)))
+}
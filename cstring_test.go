@@ -0,0 +1,236 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package unsafeslice_test
+
+import (
+	"fmt"
+	"testing"
+	"unicode/utf16"
+	"unsafe"
+
+	"github.com/bcmills/unsafeslice"
+)
+
+func TestOfCStringN(t *testing.T) {
+	buf := []byte("Hello\x00world")
+	cases := []struct {
+		max  int
+		want string
+		ok   bool
+	}{
+		{max: len(buf), want: "Hello", ok: true},
+		{max: 6, want: "Hello", ok: true},
+		{max: 5, want: "Hello", ok: false},
+		{max: 3, want: "Hel", ok: false},
+		{max: 0, want: "", ok: false},
+	}
+	for _, tc := range cases {
+		got, ok := unsafeslice.OfCStringN(&buf[0], tc.max)
+		if string(got) != tc.want || ok != tc.ok {
+			t.Errorf("OfCStringN(%q, %d) = %q, %v; want %q, %v", buf, tc.max, got, ok, tc.want, tc.ok)
+		}
+	}
+
+	signed := []int8{'h', 'i', 0, 'x'}
+	if got, ok := unsafeslice.OfCStringN(&signed[0], len(signed)); string(got) != "hi" || !ok {
+		t.Errorf("OfCStringN(%v, %d) = %q, %v; want %q, true", signed, len(signed), got, ok, "hi")
+	}
+
+	if got, ok := unsafeslice.OfCStringN((*byte)(nil), 8); got != nil || ok {
+		t.Errorf("OfCStringN(nil, 8) = %q, %v; want nil, false", got, ok)
+	}
+}
+
+func TestOfCStringIn(t *testing.T) {
+	buf := []byte("Hello\x00world")
+	cases := []struct {
+		start int
+		want  string
+		ok    bool
+	}{
+		{start: 0, want: "Hello", ok: true},
+		{start: 3, want: "lo", ok: true},
+		{start: 5, want: "", ok: true},
+		{start: 6, want: "world", ok: false},
+		{start: len(buf), want: "", ok: false},
+	}
+	for _, tc := range cases {
+		got, ok := unsafeslice.OfCStringIn(buf, tc.start)
+		if string(got) != tc.want || ok != tc.ok {
+			t.Errorf("OfCStringIn(%q, %d) = %q, %v; want %q, %v", buf, tc.start, got, ok, tc.want, tc.ok)
+		}
+	}
+
+	for _, start := range []int{-1, len(buf) + 1} {
+		func() {
+			defer func() {
+				if msg := recover(); msg != nil {
+					t.Logf("recovered: %v", msg)
+				} else {
+					t.Errorf("OfCStringIn(%q, %d) failed to panic as expected.", buf, start)
+				}
+			}()
+			unsafeslice.OfCStringIn(buf, start)
+		}()
+	}
+}
+
+func TestOfCStringSpan(t *testing.T) {
+	buf := []byte("foo\x00\x00bar\x00")
+	var got []string
+	for i := 0; i < len(buf); {
+		data, total := unsafeslice.OfCStringSpan(&buf[i])
+		got = append(got, string(data))
+		i += total
+	}
+	if want := []string{"foo", "", "bar"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("OfCStringSpan over %q yielded %q; want %q", buf, got, want)
+	}
+}
+
+func TestInt8ToBytes(t *testing.T) {
+	signed := []int8{'h', 'i', 0, 'x'}
+	if got := unsafeslice.Int8ToBytes(&signed[0]); string(got) != "hi" || unsafe.Pointer(&got[0]) != unsafe.Pointer(&signed[0]) {
+		t.Errorf("Int8ToBytes(%v) = %q; want %q aliasing the input", signed, got, "hi")
+	}
+	unsigned := []uint8("hi\x00x")
+	if got := unsafeslice.Uint8ToBytes(&unsigned[0]); string(got) != "hi" || &got[0] != &unsigned[0] {
+		t.Errorf("Uint8ToBytes(%q) = %q; want %q aliasing the input", unsigned, got, "hi")
+	}
+	if got := unsafeslice.Int8ToBytes(nil); got != nil {
+		t.Errorf("Int8ToBytes(nil) = %q; want nil", got)
+	}
+}
+
+func TestCStringsUntilDoubleNull(t *testing.T) {
+	buf := []byte("A=1\x00B=2\x00\x00C=3\x00")
+	var got []string
+	for _, s := range unsafeslice.CStringsUntilDoubleNull(&buf[0]) {
+		got = append(got, string(s))
+	}
+	if want := []string{"A=1", "B=2"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("CStringsUntilDoubleNull(%q) = %q; want %q", buf, got, want)
+	}
+
+	if ss := unsafeslice.CStringsUntilDoubleNull(&buf[len(buf)-1]); ss != nil {
+		t.Errorf("CStringsUntilDoubleNull of an empty block = %q; want nil", ss)
+	}
+
+	wbuf := utf16.Encode([]rune("A=\u00e9\x00B=\U0001F600\x00\x00C=3\x00"))
+	got = nil
+	for _, s := range unsafeslice.CStringsUntilDoubleNull(&wbuf[0]) {
+		got = append(got, string(utf16.Decode(s)))
+	}
+	if want := []string{"A=\u00e9", "B=\U0001F600"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("CStringsUntilDoubleNull(%x) = %q; want %q", wbuf, got, want)
+	}
+}
+
+func TestGoString(t *testing.T) {
+	buf := []byte("Hello\x00world\x00")
+
+	s := unsafeslice.GoString(&buf[0])
+	if s != "Hello" {
+		t.Errorf("GoString(%q) = %q; want %q", buf, s, "Hello")
+	}
+	if s := unsafeslice.GoStringN(&buf[0], 8); s != "Hello\x00wo" {
+		t.Errorf("GoStringN(%q, 8) = %q; want %q", buf, s, "Hello\x00wo")
+	}
+
+	// The result must not alias buf.
+	copy(buf, "Adios")
+	if s != "Hello" {
+		t.Errorf("GoString result changed to %q after mutating its source.", s)
+	}
+
+	if s := unsafeslice.GoString[byte](nil); s != "" {
+		t.Errorf("GoString(nil) = %q; want %q", s, "")
+	}
+	if s := unsafeslice.GoStringN[byte](nil, 4); s != "" {
+		t.Errorf("GoStringN(nil, 4) = %q; want %q", s, "")
+	}
+}
+
+func TestStrLenN(t *testing.T) {
+	buf := []int32{1, 2, 3, 0, 5}
+	cases := []struct {
+		max  int
+		want int
+		ok   bool
+	}{
+		{max: len(buf), want: 3, ok: true},
+		{max: 4, want: 3, ok: true},
+		{max: 3, want: 3, ok: false},
+		{max: 1, want: 1, ok: false},
+	}
+	for _, tc := range cases {
+		n, ok := unsafeslice.StrLenN(&buf[0], tc.max)
+		if n != tc.want || ok != tc.ok {
+			t.Errorf("StrLenN(%v, %d) = %v, %v; want %v, %v", buf, tc.max, n, ok, tc.want, tc.ok)
+		}
+	}
+
+	if n, ok := unsafeslice.StrLenN((*int32)(nil), 8); n != 0 || ok {
+		t.Errorf("StrLenN(nil, 8) = %v, %v; want 0, false", n, ok)
+	}
+}
+
+func TestOfWString(t *testing.T) {
+	const want = "Hello, 世界 🌍"
+	buf := append(utf16.Encode([]rune(want)), 0, 'x')
+
+	if n := unsafeslice.WStrLen(&buf[0]); n != len(buf)-2 {
+		t.Errorf("WStrLen(%v) = %v; want %v", buf, n, len(buf)-2)
+	}
+
+	ws := unsafeslice.OfWString(&buf[0])
+	if len(ws) != len(buf)-2 || &ws[0] != &buf[0] {
+		t.Errorf("OfWString(%v) = %v; want alias of %v", buf, ws, buf[:len(buf)-2])
+	}
+
+	if s := unsafeslice.WStringToString(&buf[0]); s != want {
+		t.Errorf("WStringToString(%v) = %q; want %q", buf, s, want)
+	}
+
+	if s := unsafeslice.WStringToString(nil); s != "" {
+		t.Errorf("WStringToString(nil) = %q; want %q", s, "")
+	}
+}
+
+func TestTypedCString(t *testing.T) {
+	buf := []int32{3, -1, 7, 0, 5}
+	s := unsafeslice.TypedCString(&buf[0])
+	if len(s) != 3 || cap(s) != 3 || &s[0] != &buf[0] {
+		t.Errorf("TypedCString(%v) = %v (cap %d); want alias of %v", buf, s, cap(s), buf[:3])
+	}
+
+	if s := unsafeslice.TypedCString(&buf[3]); s != nil {
+		t.Errorf("TypedCString(&0) = %v; want nil", s)
+	}
+	if s := unsafeslice.TypedCString[int32](nil); s != nil {
+		t.Errorf("TypedCString(nil) = %v; want nil", s)
+	}
+}
+
+func TestSetStrLenLimit(t *testing.T) {
+	unsafeslice.SetStrLenLimit(4)
+	defer unsafeslice.SetStrLenLimit(0)
+
+	if s := unsafeslice.GoString(&[]byte("abc\x00")[0]); s != "abc" {
+		t.Errorf("GoString(%q) = %q with limit 4", "abc\x00", s)
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("GoString of string longer than the limit failed to panic as expected.")
+		}
+	}()
+	unsafeslice.GoString(&[]byte("abcd\x00")[0])
+}
@@ -0,0 +1,36 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package unsafeslice
+
+import (
+	"encoding/binary"
+	"unsafe"
+)
+
+// NativeIsLittleEndian reports whether the host stores multi-byte values with
+// the least significant byte first.
+//
+// Reinterpreting bytes as multi-byte values, as by ConvertAt, yields values in
+// the host's byte order. Programs that are only correct on little-endian hosts
+// can check NativeIsLittleEndian during initialization to fail fast elsewhere.
+func NativeIsLittleEndian() bool {
+	return littleEndian
+}
+
+// NativeByteOrder returns the byte order of the host: either binary.LittleEndian
+// or binary.BigEndian.
+func NativeByteOrder() binary.ByteOrder {
+	if littleEndian {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// littleEndian reports whether the host stores multi-byte values with the
+// least significant byte first.
+var littleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
@@ -0,0 +1,39 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package unsafeslice
+
+import (
+	"io"
+)
+
+// A FixedWriter is an io.Writer that writes into a fixed-size slice, such as
+// one obtained from SetAt or MmapRecords over a memory-mapped file, without
+// ever growing it.
+type FixedWriter struct {
+	b []byte
+	n int // the number of bytes written to b so far
+}
+
+// NewFixedWriter returns a FixedWriter that writes into b, starting at b[0].
+func NewFixedWriter(b []byte) *FixedWriter {
+	return &FixedWriter{b: b}
+}
+
+// Write copies as much of p as fits into the unwritten portion of the
+// underlying slice. If all of p does not fit, Write returns the number of
+// bytes copied and io.ErrShortWrite.
+func (w *FixedWriter) Write(p []byte) (int, error) {
+	n := copy(w.b[w.n:], p)
+	w.n += n
+	if n < len(p) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
+
+// Written returns the number of bytes written to the underlying slice so far.
+func (w *FixedWriter) Written() int {
+	return w.n
+}
@@ -0,0 +1,38 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package unsafeslice_test
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/bcmills/unsafeslice"
+)
+
+func TestFixedWriter(t *testing.T) {
+	buf := make([]byte, 8)
+	w := unsafeslice.NewFixedWriter(buf)
+
+	if n, err := fmt.Fprintf(w, "%d-%d", 12, 34); n != 5 || err != nil {
+		t.Errorf("Fprintf(w, \"%%d-%%d\", 12, 34) = %d, %v; want 5, <nil>", n, err)
+	}
+	if n, err := io.WriteString(w, "world"); n != 3 || err != io.ErrShortWrite {
+		t.Errorf("WriteString(w, %q) = %d, %v; want 3, %v", "world", n, err, io.ErrShortWrite)
+	}
+	if n, err := w.Write([]byte("!")); n != 0 || err != io.ErrShortWrite {
+		t.Errorf("Write on full writer = %d, %v; want 0, %v", n, err, io.ErrShortWrite)
+	}
+	if n, err := w.Write(nil); n != 0 || err != nil {
+		t.Errorf("Write(nil) on full writer = %d, %v; want 0, <nil>", n, err)
+	}
+
+	if got := w.Written(); got != len(buf) {
+		t.Errorf("Written() = %d; want %d", got, len(buf))
+	}
+	if want := "12-34wor"; string(buf) != want {
+		t.Errorf("buf = %q; want %q", buf, want)
+	}
+}
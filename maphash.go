@@ -2,9 +2,6 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// +build go1.14
-// +build !unsafe race
-
 package unsafeslice
 
 import (
@@ -0,0 +1,19 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package unsafeslice
+
+import (
+	"hash/maphash"
+	"testing"
+)
+
+// TestChecksumUsesMaphash verifies that the checksum uses maphash, regardless
+// of the build tags.
+func TestChecksumUsesMaphash(t *testing.T) {
+	var h interface{} = newHash()
+	if _, ok := h.(*maphash.Hash); !ok {
+		t.Errorf("newHash() = %T; want *maphash.Hash", h)
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !paranoid
+// +build !paranoid
+
+package unsafeslice
+
+const paranoidEnabled = false
+
+// paranoidOfString is never called unless paranoidEnabled is true.
+func paranoidOfString(s string) []byte { panic("unreachable") }
+
+// paranoidOfStringScoped is never called unless paranoidEnabled is true.
+func paranoidOfStringScoped(s string) ([]byte, func()) { panic("unreachable") }
+
+// paranoidAsString is never called unless paranoidEnabled is true.
+func paranoidAsString(b []byte) string { panic("unreachable") }
+
+// checkParanoid has nothing to check unless paranoidEnabled is true.
+func checkParanoid(gc bool) {}
+
+// paranoidCovers has no checks to consult unless paranoidEnabled is true.
+func paranoidCovers(start, end uintptr) bool { return false }
+
+// releaseParanoidChecks has nothing to release unless paranoidEnabled is true.
+func releaseParanoidChecks(start, end uintptr) {}
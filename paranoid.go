@@ -0,0 +1,164 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build paranoid
+// +build paranoid
+
+package unsafeslice
+
+import (
+	"runtime"
+	"sync"
+)
+
+// This file contains declarations for “paranoid” mode, which replaces the
+// aliasing in OfString and AsString with defensive copies and compares each
+// copy against its original at every garbage collection.
+//
+// Paranoid mode gives up all of the performance benefits of this package in
+// exchange for deterministic detection of mutations, which makes it a useful
+// oracle for fuzz targets.
+
+const paranoidEnabled = true
+
+// paranoidCheckCycles is the number of garbage collections at which each
+// paranoid check is repeated before it is released. Releasing checks bounds the
+// memory retained by long-running programs.
+const paranoidCheckCycles = 4
+
+// paranoidOfString returns a copy of s and registers a check that the copy is
+// never mutated.
+func paranoidOfString(s string) []byte {
+	b := []byte(s)[:len(s):len(s)]
+	registerParanoidCheck(b, s)
+	return b
+}
+
+// paranoidOfStringScoped returns a copy of s and a function that checks
+// immediately that the copy has not been mutated.
+func paranoidOfStringScoped(s string) (b []byte, done func()) {
+	b = []byte(s)[:len(s):len(s)]
+	var once sync.Once
+	return b, func() {
+		once.Do(func() {
+			if string(b) != s {
+				reportMutation(b, s, nil)
+			}
+		})
+	}
+}
+
+// paranoidAsString returns a copy of b and registers a check that b is never
+// again mutated.
+func paranoidAsString(b []byte) string {
+	s := string(b)
+	registerParanoidCheck(b, s)
+	return s
+}
+
+type paranoidCheck struct {
+	b      []byte
+	orig   string
+	cycles int // the number of garbage collections at which b has been checked
+}
+
+var paranoid struct {
+	mu     sync.Mutex
+	checks []paranoidCheck
+}
+
+func registerParanoidCheck(b []byte, orig string) {
+	if len(b) == 0 {
+		return
+	}
+	paranoid.mu.Lock()
+	paranoid.checks = append(paranoid.checks, paranoidCheck{b: b, orig: orig})
+	paranoid.mu.Unlock()
+}
+
+// gcSentinel is an object whose finalizer runs once per garbage collection.
+// It contains a pointer so that it is not batched with other objects by the
+// tiny allocator, which could otherwise delay its finalizer indefinitely.
+type gcSentinel struct{ _ *byte }
+
+func init() {
+	runtime.SetFinalizer(new(gcSentinel), runParanoidChecks)
+}
+
+// runParanoidChecks runs the paranoid checks for a garbage collection, then
+// re-arms itself to run after the next one.
+func runParanoidChecks(*gcSentinel) {
+	runtime.SetFinalizer(new(gcSentinel), runParanoidChecks)
+	checkParanoid(true)
+}
+
+// releaseParanoidChecks compares every pending paranoid check that covers any
+// of the addresses [start, end) against its original contents one last time,
+// and then discards it.
+func releaseParanoidChecks(start, end uintptr) {
+	paranoid.mu.Lock()
+	checks := paranoid.checks[:0]
+	var released []paranoidCheck
+	for _, c := range paranoid.checks {
+		if bytesOverlap(c.b, start, end) {
+			released = append(released, c)
+		} else {
+			checks = append(checks, c)
+		}
+	}
+	for i := len(checks); i < len(paranoid.checks); i++ {
+		paranoid.checks[i] = paranoidCheck{}
+	}
+	paranoid.checks = checks
+	paranoid.mu.Unlock()
+
+	for _, c := range released {
+		if string(c.b) != c.orig {
+			reportMutation(c.b, c.orig, nil)
+		}
+	}
+}
+
+// paranoidCovers reports whether any pending paranoid check covers any of the
+// addresses [start, end).
+func paranoidCovers(start, end uintptr) bool {
+	paranoid.mu.Lock()
+	defer paranoid.mu.Unlock()
+	for _, c := range paranoid.checks {
+		if bytesOverlap(c.b, start, end) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkParanoid compares every pending paranoid check against its original
+// contents. If gc is true, it also counts a garbage collection against each
+// check, releasing those that have been checked paranoidCheckCycles times.
+func checkParanoid(gc bool) {
+	paranoid.mu.Lock()
+	checks := paranoid.checks[:0]
+	var failed []paranoidCheck
+	for _, c := range paranoid.checks {
+		if string(c.b) != c.orig {
+			failed = append(failed, c)
+			continue
+		}
+		if gc {
+			c.cycles++
+		}
+		if c.cycles < paranoidCheckCycles {
+			checks = append(checks, c)
+		}
+	}
+	for i := len(checks); i < len(paranoid.checks); i++ {
+		paranoid.checks[i] = paranoidCheck{}
+	}
+	paranoid.checks = checks
+	paranoid.mu.Unlock()
+
+	for _, c := range failed {
+		reportMutation(c.b, c.orig, nil)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build paranoid
+// +build paranoid
+
+package unsafeslice_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/bcmills/unsafeslice"
+)
+
+const paranoidEnabled = true
+
+// TestParanoidMutation verifies that paranoid mode detects a mutation at the
+// next garbage collection, without any call to CheckNow.
+func TestParanoidMutation(t *testing.T) {
+	detected := make(chan uintptr, 1)
+	unsafeslice.OnMutation = func(addr uintptr) {
+		select {
+		case detected <- addr:
+		default:
+		}
+	}
+	defer func() { unsafeslice.OnMutation = nil }()
+
+	b := []byte("Hello, world!")
+	_ = unsafeslice.AsString(b)
+	copy(b, "Kaboom")
+
+	for i := 0; i < 100; i++ {
+		runtime.GC()
+		select {
+		case addr := <-detected:
+			if want := uintptr(unsafe.Pointer(&b[0])); addr != want {
+				t.Errorf("OnMutation called with %#x; want %#x", addr, want)
+			}
+			return
+		case <-time.After(time.Millisecond):
+		}
+	}
+	t.Errorf("mutation not detected after 100 garbage collections")
+}
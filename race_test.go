@@ -0,0 +1,82 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build race
+// +build race
+
+package unsafeslice_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcmills/unsafeslice"
+)
+
+const raceEnabled = true
+
+// TestRaceMutationChecks verifies that mutation checks remain active under the
+// race detector, even if the program is also built with the "unsafe" tag.
+func TestRaceMutationChecks(t *testing.T) {
+	if !unsafeslice.SafetyChecksEnabled() {
+		t.Fatalf("SafetyChecksEnabled() = false under the race detector")
+	}
+
+	var got []uintptr
+	unsafeslice.OnMutation = func(addr uintptr) { got = append(got, addr) }
+	defer func() { unsafeslice.OnMutation = nil }()
+
+	// Skip the eager recheck so that the race detector itself does not flag the
+	// deliberate mutation below.
+	unsafeslice.SetEagerRaceCheck(false)
+	defer unsafeslice.SetEagerRaceCheck(true)
+
+	b := unsafeslice.Freeze([]byte("Hello, world!"))
+	copy(b, "Kaboom")
+	unsafeslice.CheckNow()
+	if len(got) == 0 {
+		t.Errorf("CheckNow did not detect a mutation of a frozen slice.")
+	}
+
+	// Restore the original contents so that the check passes when it is
+	// repeated after OnMutation is reset.
+	copy(b, "Hello,")
+}
+
+// TestBackgroundCheckerRace verifies that the race detector still reports a
+// mutation of checked data while a background checker is running, even if the
+// mutation is followed by another checked conversion.
+func TestBackgroundCheckerRace(t *testing.T) {
+	if paranoidEnabled {
+		t.Skip("AsString copies its argument in paranoid mode, so the mutation does not race")
+	}
+	if os.Getenv("UNSAFESLICE_TEST_BACKGROUND_RACE") != "" {
+		// Report the mutation only as a data race, not as a failed checksum.
+		unsafeslice.OnMutation = func(uintptr) {}
+
+		stop := unsafeslice.StartBackgroundChecker(time.Millisecond)
+		b := []byte("Hello, world!")
+		_ = unsafeslice.AsString(b)
+		b[0] = 'J'
+		_ = unsafeslice.AsString([]byte("Hello, world!"))
+		time.Sleep(20 * time.Millisecond)
+		stop()
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run="+t.Name(), "-test.v")
+	cmd.Env = append(os.Environ(), "UNSAFESLICE_TEST_BACKGROUND_RACE=1")
+	out := new(bytes.Buffer)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	err := cmd.Run()
+	t.Logf("%s:\n%s", strings.Join(cmd.Args, " "), out)
+	if err == nil || !strings.Contains(out.String(), "DATA RACE") {
+		t.Errorf("Test subprocess did not report a data race; want a report of the mutation.")
+	}
+}
@@ -2,12 +2,22 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// +build !unsafe race
+//go:build !unsafe || race || paranoid
+// +build !unsafe race paranoid
 
 package unsafeslice
 
 import (
 	"fmt"
+	"hash"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
 
 	"github.com/bcmills/unsafeslice/internal/eventually"
 )
@@ -15,16 +25,168 @@ import (
 // This file contains declarations for “less unsafe” mode,
 // which makes a best-effort attempt to detect string mutations.
 
+// SafetyChecksEnabled reports whether OfString, AsString, and related
+// functions make a best-effort attempt to detect mutations.
+//
+// It returns false if the program was built with the "unsafe" tag and without
+// the race detector, or if checks have been disabled by
+// SetMutationChecksEnabled.
+func SafetyChecksEnabled() bool {
+	return atomic.LoadInt32(&mutationChecksDisabled) == 0
+}
+
+// SetMutationChecksEnabled enables or disables mutation checks for subsequent
+// calls to OfString, AsString, and related functions. Checks are enabled by
+// default.
+//
+// SetMutationChecksEnabled allows the checks to be controlled at run time, for
+// example from a command-line flag. To remove them entirely, build with the
+// "unsafe" tag instead.
+func SetMutationChecksEnabled(enabled bool) {
+	var disabled int32
+	if !enabled {
+		disabled = 1
+	}
+	atomic.StoreInt32(&mutationChecksDisabled, disabled)
+}
+
+// mutationChecksDisabled is nonzero if SetMutationChecksEnabled(false) has
+// been called more recently than SetMutationChecksEnabled(true).
+var mutationChecksDisabled int32
+
+// SetEagerRaceCheck enables or disables the goroutine that OfString, AsString,
+// and related functions start to re-read each checked slice when the race
+// detector is enabled. Eager checks are enabled by default.
+//
+// Disabling eager checks leaves only the checksum comparisons made by
+// finalizers, CheckNow, and StartBackgroundChecker, which are less sensitive
+// but avoid starting a goroutine per call. That may be worthwhile in tests that
+// convert very many strings under the race detector.
+func SetEagerRaceCheck(enabled bool) {
+	var disabled int32
+	if !enabled {
+		disabled = 1
+	}
+	atomic.StoreInt32(&eagerRaceCheckDisabled, disabled)
+}
+
+// eagerRaceCheckDisabled is nonzero if SetEagerRaceCheck(false) has been
+// called more recently than SetEagerRaceCheck(true).
+var eagerRaceCheckDisabled int32
+
+// OnMutation, if non-nil, is called with the address of the mutated data
+// instead of panicking when a mutation check fails.
+//
+// OnMutation may be called from an arbitrary goroutine, including a finalizer.
+// It should be set during program initialization, before any strings are
+// converted.
+var OnMutation func(addr uintptr)
+
+// SetMutationHash sets the function used to construct the hash with which
+// mutation checks compute checksums. A nil newHash restores the default hash.
+//
+// newHash must return a freshly-initialized hash.Hash64 on every call. Checks
+// that are already pending continue to use the hash that was in effect when
+// they started.
+func SetMutationHash(newHash func() hash.Hash64) {
+	mutationHash.Store(mutationHashFunc(newHash))
+}
+
+// mutationHashFunc is the concrete type stored in mutationHash, which
+// requires a consistent type for all stored values.
+type mutationHashFunc func() hash.Hash64
+
+// mutationHash holds the mutationHashFunc set by SetMutationHash.
+var mutationHash atomic.Value
+
+// SetRecheckObserver sets a function to be called each time a mutation check
+// makes its final recheck, with the times at which the check started and at
+// which it was rechecked. A nil f removes the observer.
+//
+// The final recheck is the one made when the garbage collector finalizes the
+// check, or, for OfStringScoped, when done is called. The interval between the
+// two times is the window in which a finalized check can detect a mutation,
+// which depends on the garbage collector and may be much shorter or longer than
+// the lifetime of the checked data.
+//
+// Other rechecks, such as those made by CheckNow, StartBackgroundChecker, or
+// the goroutine started under the race detector, are not observed. Neither are
+// checks that CloneToGo may have released, checks made in paranoid mode, or
+// checks that are already pending when f is set, which do not record their
+// start times.
+//
+// f may be called from an arbitrary goroutine, including a finalizer, and
+// must not block.
+func SetRecheckObserver(f func(started, rechecked time.Time)) {
+	recheckObserver.Store(recheckObserverFunc(f))
+}
+
+// recheckObserverFunc is the concrete type stored in recheckObserver, which
+// requires a consistent type for all stored values.
+type recheckObserverFunc func(started, rechecked time.Time)
+
+// recheckObserver holds the recheckObserverFunc set by SetRecheckObserver.
+var recheckObserver atomic.Value
+
 // maybeDetectMutations makes a best effort to detect mutations and lifetime
 // errors on the slice b. It is most effective when run under the race detector.
 func maybeDetectMutations(b []byte) {
-	if len(b) == 0 {
+	if len(b) == 0 || !SafetyChecksEnabled() {
 		return
 	}
+	startMutationCheck(newMutationChecker(b))
+}
 
+// maybeDetectKeyMutations is like maybeDetectMutations, but always records
+// the stack that started the check, so that a failed check can report it.
+func maybeDetectKeyMutations(b []byte) {
+	if len(b) == 0 || !SafetyChecksEnabled() {
+		return
+	}
+	c := newMutationChecker(b)
+	if c.stack == nil {
+		c.stack = callers()
+	}
+	startMutationCheck(c)
+}
+
+// maybeDetectMutationsAll is like maybeDetectMutations, but checks all of the
+// slices in bs with a single mutationChecker.
+func maybeDetectMutationsAll(bs [][]byte) {
+	if !SafetyChecksEnabled() {
+		return
+	}
+	n := 0
+	for _, b := range bs {
+		n += len(b)
+	}
+	if n == 0 {
+		return
+	}
+	startMutationCheck(newBatchMutationChecker(bs))
+}
+
+// maybeDetectMutationsScoped is like maybeDetectMutations, but instead of
+// deferring the check to a finalizer it returns a function that repeats the
+// check immediately and then discards it.
+func maybeDetectMutationsScoped(b []byte) (done func()) {
+	if len(b) == 0 || !SafetyChecksEnabled() {
+		return func() {}
+	}
+
+	// Neither the race-detector goroutine nor the finalizer is safe here: either
+	// could read b after done returns, when the caller is free to reuse it.
 	c := newMutationChecker(b)
+	c.register()
+	var once sync.Once
+	return func() { once.Do(c.finalize) }
+}
+
+// startMutationCheck registers c and arranges for it to be rechecked later.
+func startMutationCheck(c *mutationChecker) {
+	c.register()
 
-	if raceEnabled {
+	if raceEnabled && atomic.LoadInt32(&eagerRaceCheckDisabled) == 0 {
 		// Start a goroutine that reads from the slice and does not have a
 		// happens-before relationship with any other event in the program.
 		//
@@ -50,33 +212,533 @@ func maybeDetectMutations(b []byte) {
 	// much too early — before a dangerous mutation has even occurred. It's better
 	// than nothing, but not an adequate substitute for the race-enabled version
 	// of this check.
-	eventually.SetFinalizer(c, (*mutationChecker).recheck)
+	eventually.SetFinalizer(c, (*mutationChecker).finalize)
 }
 
+// CheckNow synchronously repeats every pending mutation check, panicking (or
+// calling OnMutation) for each one that fails.
+//
+// A check is pending from the call that starts it, such as OfString, until its
+// finalizer has run or it is evicted by newer checks.
+//
+// CheckNow is mainly useful in tests, to detect mutations deterministically
+// instead of waiting for the garbage collector.
+func CheckNow() {
+	recheckPending()
+	checkParanoid(false)
+}
+
+// recheckPending repeats every pending mutation check.
+func recheckPending() {
+	checks := make([]mutationCheck, 0, PendingCheckCount())
+	forEachPending(func(pc *pendingCheck) bool {
+		checks = append(checks, pc.check)
+		return true
+	})
+	for i := range checks {
+		checks[i].recheck()
+	}
+}
+
+// StartBackgroundChecker starts a single goroutine that repeats every pending
+// mutation check, as if by CheckNow, once per interval, and returns a function
+// that stops it. stop waits for any sweep in progress to finish, and calls to
+// stop after the first have no effect. StartBackgroundChecker panics if
+// interval is not positive.
+//
+// The sweeps compare checksums, so they detect a mutation within about one
+// interval instead of whenever the garbage collector finalizes its check. They
+// cover only the pending checks, which are the most recent ones; checks that
+// have been evicted are left to their finalizers.
+//
+// The sweeps supplement, but do not replace, the goroutine that each check
+// starts under the race detector (see SetEagerRaceCheck). A sweep synchronizes
+// with some of the calls that start checks, so a mutation followed by another
+// such call may be ordered before the sweep's read and not be reported as a
+// data race.
+func StartBackgroundChecker(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		panic(fmt.Sprintf("StartBackgroundChecker with non-positive interval %v", interval))
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				recheckPending()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-exited
+		})
+	}
+}
+
+// PendingCheckCount returns the number of mutation checks that CheckNow would
+// currently repeat. It never exceeds an internal limit, beyond which the
+// oldest checks are evicted and left to their finalizers alone.
+func PendingCheckCount() int {
+	return int(atomic.LoadInt32(&pending.count))
+}
+
+// releaseMutationChecks removes every pending check that covers any of the n
+// bytes starting at p and repeats it one last time.
+//
+// It also logs the released range, so that a check that is not pending, because
+// it was evicted or raced with the release, does not report later mutations of
+// the released bytes.
+func releaseMutationChecks(p unsafe.Pointer, n uintptr) {
+	if n == 0 {
+		return
+	}
+	start := uintptr(p)
+	end := start + n
+
+	var checks []mutationCheck
+	forEachPending(func(pc *pendingCheck) bool {
+		if pc.check.overlaps(start, end) {
+			checks = append(checks, pc.check)
+			pc.clear()
+		}
+		return true
+	})
+
+	for i := range checks {
+		checks[i].recheck()
+	}
+	releaseParanoidChecks(start, end)
+
+	released.mu.Lock()
+	released.ranges[released.n%maxReleasedRanges] = addrRange{start, end}
+	atomic.StoreUint64(&released.n, released.n+1)
+	released.mu.Unlock()
+}
+
+// maxReleasedRanges is the capacity of the log of released ranges.
+const maxReleasedRanges = 64
+
+// released is a log of the ranges most recently released by
+// releaseMutationChecks.
+var released struct {
+	// n is the number of calls to releaseMutationChecks so far. It is written
+	// only with mu held, but may be loaded atomically without it.
+	// (It comes first to keep it 64-bit aligned on 32-bit platforms.)
+	n uint64
+
+	mu sync.Mutex
+
+	// ranges[i%maxReleasedRanges] is the range of the release that made n = i+1.
+	ranges [maxReleasedRanges]addrRange
+}
+
+// An addrRange is the range of addresses [start, end).
+type addrRange struct {
+	start, end uintptr
+}
+
+// checkWritable panics if any of the n bytes starting at p are covered by a
+// pending mutation check or a paranoid check, which marks them as read-only,
+// using op as the name of the operation in the panic message.
+func checkWritable(op string, p unsafe.Pointer, n uintptr) {
+	if n == 0 || !SafetyChecksEnabled() {
+		return
+	}
+	start := uintptr(p)
+	end := start + n
+
+	covered := false
+	forEachPending(func(pc *pendingCheck) bool {
+		covered = pc.check.overlaps(start, end)
+		return !covered
+	})
+	if covered || paranoidCovers(start, end) {
+		panic(fmt.Sprintf("%s: data at 0x%x is read-only, as from OfString, "+
+			"but would be overwritten", op, start))
+	}
+}
+
+// SetCheckerDebug enables or disables recording the call stack that starts each
+// mutation check, along with a copy of the checked data, so that a failed check
+// can report the offset and values of the first mutated byte. Recording is
+// disabled by default, since it makes every check considerably more expensive.
+func SetCheckerDebug(enabled bool) {
+	var debug int32
+	if enabled {
+		debug = 1
+	}
+	atomic.StoreInt32(&checkerDebug, debug)
+}
+
+// checkerDebug is nonzero if SetCheckerDebug(true) has been called more
+// recently than SetCheckerDebug(false).
+var checkerDebug int32
+
+// DumpPendingCheckers writes to w the number of pending mutation checks,
+// followed by the call sites that started them and the number of checks
+// pending from each site, most frequent first. Call sites are recorded only for
+// checks started while SetCheckerDebug(true) is in effect.
+//
+// DumpPendingCheckers is intended for diagnosing checks that accumulate faster
+// than the garbage collector finalizes them.
+func DumpPendingCheckers(w io.Writer) error {
+	count := PendingCheckCount()
+	sites := make(map[string]int)
+	forEachPending(func(pc *pendingCheck) bool {
+		if len(pc.check.stack) > 0 {
+			sites[formatStack(pc.check.stack)]++
+		}
+		return true
+	})
+
+	type site struct {
+		stack string
+		n     int
+	}
+	sorted := make([]site, 0, len(sites))
+	for stack, n := range sites {
+		sorted = append(sorted, site{stack, n})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].n != sorted[j].n {
+			return sorted[i].n > sorted[j].n
+		}
+		return sorted[i].stack < sorted[j].stack
+	})
+
+	if _, err := fmt.Fprintf(w, "%d pending mutation checks\n", count); err != nil {
+		return err
+	}
+	for _, s := range sorted {
+		if _, err := fmt.Fprintf(w, "\n%d checks started at:\n%s", s.n, s.stack); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatStack formats the frames of stack outside of this package, one per
+// line in the style of a goroutine traceback.
+func formatStack(stack []uintptr) string {
+	const pkgPrefix = "github.com/bcmills/unsafeslice."
+
+	var sb strings.Builder
+	frames := runtime.CallersFrames(stack)
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, pkgPrefix) {
+			fmt.Fprintf(&sb, "\t%s\n\t\t%s:%d\n", f.Function, f.File, f.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
+
+// maxPendingChecks is the capacity of the ring buffer of pending checks.
+const maxPendingChecks = 1024
+
+// pending is a ring buffer recording the state of recent mutationCheckers
+// whose finalizers have not yet run. It stores copies of their mutationChecks
+// rather than pointers so that it does not prevent those finalizers from
+// running, and each finalizer clears its slot so that the ring does not keep
+// the checked data alive any longer than the mutationChecker itself.
+//
+// Each slot has its own lock, and register claims the next slot with an atomic
+// increment, so that concurrent checks do not contend on a single lock.
+var pending struct {
+	next  uint32 // the number of checks registered so far; accessed atomically
+	count int32  // the number of occupied slots; accessed atomically
+	ring  [maxPendingChecks]pendingCheck
+}
+
+type pendingCheck struct {
+	mu    sync.Mutex
+	owner uintptr // the address of the mutationChecker, or 0 if the slot is free
+	check mutationCheck
+}
+
+// clear frees the slot pc, which must be occupied and locked.
+func (pc *pendingCheck) clear() {
+	pc.owner = 0
+	pc.check = mutationCheck{}
+	atomic.AddInt32(&pending.count, -1)
+}
+
+// forEachPending calls f for each occupied slot in the ring, with that slot
+// locked, until f returns false.
+func forEachPending(f func(pc *pendingCheck) bool) {
+	if atomic.LoadInt32(&pending.count) == 0 {
+		return
+	}
+	for i := range pending.ring {
+		pc := &pending.ring[i]
+		pc.mu.Lock()
+		more := pc.owner == 0 || f(pc)
+		pc.mu.Unlock()
+		if !more {
+			return
+		}
+	}
+}
+
+// A mutationChecker is a mutationCheck in progress, which is rechecked when it
+// is finalized.
 type mutationChecker struct {
+	mutationCheck
+	slot    int       // the index of c in pending.ring
+	started time.Time // the time at which c was created, if SetRecheckObserver was in effect
+}
+
+// A mutationCheck holds what is needed to recheck the checked data and report
+// a mutation: no more, since pending.ring stores a copy of it.
+type mutationCheck struct {
+	b        []byte
+	checksum uint64
+	batch    []checkedSlice   // further slices checked along with b, for OfStrings
+	newHash  mutationHashFunc // nil for the default hash
+	epoch    uint64           // the value of released.n when the check was registered
+
+	// If SetCheckerDebug(true) was in effect when the check started, stack is
+	// the stack that started it and orig is the original contents of b and
+	// batch, concatenated.
+	stack []uintptr
+	orig  string
+}
+
+// A checkedSlice is a slice checked by a batch mutationChecker, along with its
+// original checksum.
+type checkedSlice struct {
 	b        []byte
 	checksum uint64
 }
 
 func newMutationChecker(b []byte) *mutationChecker {
-	c := &mutationChecker{b: b}
-	c.checksum = c.sum64()
+	c := &mutationChecker{mutationCheck: mutationCheck{b: b}}
+	c.init()
+	return c
+}
+
+// init computes the initial checksums of c, and records the stack that created
+// it and the original data if SetCheckerDebug(true) is in effect.
+func (c *mutationChecker) init() {
+	c.newHash, _ = mutationHash.Load().(mutationHashFunc)
+	c.checksum = c.sum64Of(c.b)
+	for i := range c.batch {
+		c.batch[i].checksum = c.sum64Of(c.batch[i].b)
+	}
+	if atomic.LoadInt32(&checkerDebug) != 0 {
+		c.stack = callers()
+		c.orig = c.snapshot()
+	}
+	if f, _ := recheckObserver.Load().(recheckObserverFunc); f != nil {
+		c.started = time.Now()
+	}
+}
+
+// callers returns the program counters of the calling goroutine's stack,
+// starting with the caller of callers.
+func callers() []uintptr {
+	pcs := make([]uintptr, 32)
+	return pcs[:runtime.Callers(2, pcs)]
+}
+
+// newBatchMutationChecker returns a mutationChecker for all of the slices in
+// bs, which must not be empty.
+func newBatchMutationChecker(bs [][]byte) *mutationChecker {
+	c := &mutationChecker{mutationCheck: mutationCheck{b: bs[0]}}
+	c.batch = make([]checkedSlice, len(bs)-1)
+	for i, b := range bs[1:] {
+		c.batch[i].b = b
+	}
+	c.init()
 	return c
 }
 
-func (c *mutationChecker) recheck() {
-	if c.sum64() != c.checksum {
-		panic(fmt.Sprintf("mutation detected in string at address 0x%012x", &c.b[0]))
+// snapshot returns a copy of the current contents of the slices checked by c,
+// concatenated in order.
+func (c *mutationCheck) snapshot() string {
+	n := len(c.b)
+	for _, s := range c.batch {
+		n += len(s.b)
+	}
+	orig := make([]byte, 0, n)
+	orig = append(orig, c.b...)
+	for _, s := range c.batch {
+		orig = append(orig, s.b...)
+	}
+	return string(orig)
+}
+
+// register adds c to the pending checks, evicting the oldest check if the ring
+// is full.
+func (c *mutationChecker) register() {
+	c.epoch = atomic.LoadUint64(&released.n)
+	c.slot = int((atomic.AddUint32(&pending.next, 1) - 1) % maxPendingChecks)
+
+	p := &pending.ring[c.slot]
+	p.mu.Lock()
+	if p.owner == 0 {
+		atomic.AddInt32(&pending.count, 1)
+	}
+	p.owner = uintptr(unsafe.Pointer(c))
+	p.check = c.mutationCheck
+	p.mu.Unlock()
+}
+
+// finalize removes c from the pending checks, if it has not already been
+// evicted, and rechecks it one last time.
+func (c *mutationChecker) finalize() {
+	p := &pending.ring[c.slot]
+	p.mu.Lock()
+	if p.owner == uintptr(unsafe.Pointer(c)) {
+		p.clear()
+	}
+	p.mu.Unlock()
+
+	c.recheck()
+	f, _ := recheckObserver.Load().(recheckObserverFunc)
+	if f != nil && !c.started.IsZero() && !c.maybeReleased() {
+		f(c.started, time.Now())
+	}
+}
+
+// maybeReleased reports whether any of the data checked by c may have been
+// released since c was registered. If the log of released ranges no longer
+// reaches back that far, maybeReleased conservatively reports true.
+func (c *mutationCheck) maybeReleased() bool {
+	if atomic.LoadUint64(&released.n) == c.epoch {
+		return false
+	}
+	released.mu.Lock()
+	defer released.mu.Unlock()
+	if released.n-c.epoch > maxReleasedRanges {
+		return true
+	}
+	for i := c.epoch; i < released.n; i++ {
+		r := released.ranges[i%maxReleasedRanges]
+		if c.overlaps(r.start, r.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps reports whether any slice checked by c overlaps the addresses
+// [start, end).
+func (c *mutationCheck) overlaps(start, end uintptr) bool {
+	if bytesOverlap(c.b, start, end) {
+		return true
+	}
+	for _, s := range c.batch {
+		if bytesOverlap(s.b, start, end) {
+			return true
+		}
+	}
+	return false
+}
+
+func bytesOverlap(b []byte, start, end uintptr) bool {
+	if len(b) == 0 {
+		return false
+	}
+	p := uintptr(unsafe.Pointer(&b[0]))
+	return p < end && start < p+uintptr(len(b))
+}
+
+// recheck reports a mutation if the data checked by c has changed, unless it
+// may have been released first.
+func (c *mutationCheck) recheck() {
+	b, orig, ok := c.mutated()
+	if !ok {
+		return
+	}
+	// Consult the release log only after finding a mutation, so that a
+	// successful recheck (such as the one made under the race detector) has no
+	// happens-before relationship with releases.
+	if !c.maybeReleased() {
+		reportMutation(b, orig, c.stack)
+	}
+}
+
+// mutated returns the first slice checked by c whose checksum no longer
+// matches, along with its original contents if c recorded them. ok is false if
+// every checksum still matches.
+func (c *mutationCheck) mutated() (b []byte, orig string, ok bool) {
+	if c.sum64Of(c.b) != c.checksum {
+		return c.b, c.origAt(0, len(c.b)), true
+	}
+	off := len(c.b)
+	for _, s := range c.batch {
+		if c.sum64Of(s.b) != s.checksum {
+			return s.b, c.origAt(off, len(s.b)), true
+		}
+		off += len(s.b)
+	}
+	return nil, "", false
+}
+
+// origAt returns the n bytes of c.orig starting at off, or the empty string if
+// c did not record its original data.
+func (c *mutationCheck) origAt(off, n int) string {
+	if c.orig == "" {
+		return ""
+	}
+	return c.orig[off : off+n]
+}
+
+// reportMutation reports that the contents of b have been mutated, either by
+// calling OnMutation or by panicking. If orig is non-empty, it holds the
+// original contents of b, and the panic message locates the first mutated
+// byte. If stack is non-empty, the panic message includes it as the place where
+// the check started.
+func reportMutation(b []byte, orig string, stack []uintptr) {
+	if f := OnMutation; f != nil {
+		f(uintptr(unsafe.Pointer(&b[0])))
+		return
+	}
+	msg := describeMutation(b, orig)
+	if len(stack) > 0 {
+		msg += "\n\ncheck started at:\n" + formatStack(stack)
 	}
+	panic(msg)
 }
 
-func (c *mutationChecker) sum64() uint64 {
-	h := newHash()
-	initHash(h)
+// describeMutation returns a message describing the first byte of b that
+// differs from orig, or only the address of b if orig is empty.
+func describeMutation(b []byte, orig string) string {
+	if orig != "" {
+		for i := range b {
+			if b[i] != orig[i] {
+				return fmt.Sprintf("mutation detected in string at address 0x%012x offset %d: %#02x -> %#02x",
+					&b[0], i, orig[i], b[i])
+			}
+		}
+	}
+	// Either there is no copy of the original data to compare against, or the
+	// checksum changed but the contents did not: the hash is nondeterministic,
+	// or the data was mutated back concurrently.
+	return fmt.Sprintf("mutation detected in string at address 0x%012x", &b[0])
+}
 
-	h.Write(c.b)
-	sum := h.Sum64()
+func (c *mutationCheck) sum64Of(b []byte) uint64 {
+	if c.newHash != nil {
+		h := c.newHash()
+		h.Write(b)
+		return h.Sum64()
+	}
 
-	disposeHash(h)
-	return sum
+	return checksum(b)
 }
@@ -0,0 +1,208 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !unsafe || race || paranoid
+// +build !unsafe race paranoid
+
+package unsafeslice
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestOnMutation(t *testing.T) {
+	var got []uintptr
+	OnMutation = func(addr uintptr) { got = append(got, addr) }
+	defer func() { OnMutation = nil }()
+
+	b := []byte("Hello, world!")
+	c := newMutationChecker(b)
+	c.recheck()
+	if len(got) != 0 {
+		t.Fatalf("OnMutation called before mutation: %#x", got)
+	}
+
+	copy(b, "Kaboom")
+	c.recheck()
+	if want := uintptr(unsafe.Pointer(&b[0])); len(got) != 1 || got[0] != want {
+		t.Errorf("OnMutation called with %#x; want [%#x]", got, want)
+	}
+}
+
+func TestDescribeMutation(t *testing.T) {
+	b := []byte("Hello, world!")
+	orig := string(b)
+	b[7] = 'W'
+
+	msg := describeMutation(b, orig)
+	t.Log(msg)
+	if want := "offset 7: 0x77 -> 0x57"; !strings.Contains(msg, want) {
+		t.Errorf("describeMutation() = %q; want substring %q", msg, want)
+	}
+
+	msg = describeMutation(b, "")
+	t.Log(msg)
+	if strings.Contains(msg, "offset") {
+		t.Errorf("describeMutation() without the original data = %q; want no offset", msg)
+	}
+}
+
+func TestPendingCheckCount(t *testing.T) {
+	before := PendingCheckCount()
+
+	b := []byte("Hello, world!")
+	c := newMutationChecker(b)
+	c.register()
+	if n := PendingCheckCount(); n != before+1 && n != maxPendingChecks {
+		t.Errorf("PendingCheckCount() = %v after register; want %v", n, before+1)
+	}
+
+	for i := 0; i < 2*maxPendingChecks; i++ {
+		newMutationChecker(b).register()
+	}
+	if n := PendingCheckCount(); n != maxPendingChecks {
+		t.Errorf("PendingCheckCount() = %v after overfilling the ring; want %v", n, maxPendingChecks)
+	}
+
+	c.finalize()
+	if n := PendingCheckCount(); n != maxPendingChecks {
+		t.Errorf("PendingCheckCount() = %v after finalizing an evicted check; want %v", n, maxPendingChecks)
+	}
+}
+
+func TestBatchMutationChecker(t *testing.T) {
+	var got []uintptr
+	OnMutation = func(addr uintptr) { got = append(got, addr) }
+	defer func() { OnMutation = nil }()
+
+	bs := [][]byte{[]byte("Hello"), nil, []byte(", "), []byte("world!")}
+	c := newBatchMutationChecker(bs)
+	c.recheck()
+	if len(got) != 0 {
+		t.Fatalf("OnMutation called before mutation: %#x", got)
+	}
+
+	bs[3][1] = 'O'
+	c.recheck()
+	if want := uintptr(unsafe.Pointer(&bs[3][0])); len(got) != 1 || got[0] != want {
+		t.Errorf("OnMutation called with %#x; want [%#x]", got, want)
+	}
+
+	b, orig, ok := c.mutated()
+	if !ok || string(b) != "wOrld!" || orig != "" {
+		t.Errorf("mutated() = %q, %q, %v; want %q, \"\", true", b, orig, ok, "wOrld!")
+	}
+
+	SetCheckerDebug(true)
+	defer SetCheckerDebug(false)
+	bs[3][1] = 'o'
+	c = newBatchMutationChecker(bs)
+	bs[3][1] = 'O'
+	b, orig, ok = c.mutated()
+	if want := "world!"; !ok || string(b) != "wOrld!" || orig != want {
+		t.Errorf("mutated() with SetCheckerDebug(true) = %q, %q, %v; want %q, %q, true", b, orig, ok, "wOrld!", want)
+	}
+}
+
+// heapSink forces values assigned to it to escape to the heap.
+var heapSink interface{}
+
+func TestReleaseMutationChecks(t *testing.T) {
+	var got []uintptr
+	OnMutation = func(addr uintptr) { got = append(got, addr) }
+	defer func() { OnMutation = nil }()
+
+	b := []byte("Hello, world!")
+	c := newMutationChecker(b[7:])
+	// Registered checks are identified by address, so c must not be on the
+	// stack, where it could move. (Outside of tests, the finalizer forces it to
+	// the heap.)
+	heapSink = c
+	defer func() { heapSink = nil }()
+	c.register()
+	before := PendingCheckCount()
+
+	releaseMutationChecks(unsafe.Pointer(&b[0]), 8)
+	if n := PendingCheckCount(); n != before-1 {
+		t.Errorf("PendingCheckCount() = %v after release; want %v", n, before-1)
+	}
+	if len(got) != 0 {
+		t.Fatalf("OnMutation called before mutation: %#x", got)
+	}
+
+	copy(b, "Kaboom, ABCDE")
+	c.finalize()
+	if len(got) != 0 {
+		t.Errorf("finalize rechecked a released check: OnMutation called with %#x", got)
+	}
+}
+
+func TestReleaseUnrelatedMutationChecks(t *testing.T) {
+	var got []uintptr
+	OnMutation = func(addr uintptr) { got = append(got, addr) }
+	defer func() { OnMutation = nil }()
+
+	b := []byte("Hello, world!")
+	c := newMutationChecker(b)
+	heapSink = c
+	defer func() { heapSink = nil }()
+	c.register()
+
+	// Releasing other data does not release b.
+	other := []byte("Hello, world!")
+	CloneToGo(other)
+	copy(b, "Kaboom")
+
+	c.finalize()
+	if want := uintptr(unsafe.Pointer(&b[0])); len(got) != 1 || got[0] != want {
+		t.Errorf("OnMutation called with %#x after releasing unrelated data; want [%#x]", got, want)
+	}
+}
+
+func TestReleaseEvictedMutationChecks(t *testing.T) {
+	var got []uintptr
+	OnMutation = func(addr uintptr) { got = append(got, addr) }
+	defer func() { OnMutation = nil }()
+
+	b := []byte("Hello, world!")
+	c := newMutationChecker(b)
+	heapSink = c
+	defer func() { heapSink = nil }()
+	c.register()
+
+	// Start enough newer checks to evict c from the ring before releasing b.
+	other := []byte("Hello, world!")
+	var others []*mutationChecker
+	for i := 0; i < maxPendingChecks+1; i++ {
+		oc := newMutationChecker(other)
+		oc.register()
+		others = append(others, oc)
+	}
+	defer func() {
+		for _, oc := range others {
+			oc.finalize()
+		}
+	}()
+
+	releaseMutationChecks(unsafe.Pointer(&b[0]), uintptr(len(b)))
+	copy(b, "Kaboom")
+
+	c.recheck()
+	c.finalize()
+	if len(got) != 0 {
+		t.Errorf("released check evicted from the ring reported a mutation: OnMutation called with %#x", got)
+	}
+
+	// A check started after the release is not affected by it.
+	c = newMutationChecker(b)
+	heapSink = c
+	c.register()
+	copy(b, "Hello,")
+	c.finalize()
+	if want := uintptr(unsafe.Pointer(&b[0])); len(got) != 1 || got[0] != want {
+		t.Errorf("OnMutation called with %#x for a check started after the release; want [%#x]", got, want)
+	}
+}
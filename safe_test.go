@@ -2,24 +2,33 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// +build !unsafe race
+//go:build !unsafe || race || paranoid
+// +build !unsafe race paranoid
 
 package unsafeslice_test
 
 import (
 	"bytes"
+	"fmt"
+	"hash"
+	"hash/fnv"
 	"os"
 	"os/exec"
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 	"unsafe"
 
 	"github.com/bcmills/unsafeslice"
 	"github.com/bcmills/unsafeslice/internal/eventually"
 )
 
+const safetyChecksEnabled = true
+
 const maxStringAllocs = 1
 
 // TestStringMutations verifies that OfString and AsString detect immediate
@@ -63,8 +72,8 @@ func TestStringMutations(t *testing.T) {
 		for {
 			runtime.GC()
 			waste = append(waste, new(uint64)) // Allocate garbage to attempt to force finalizers to run.
+			runtime.KeepAlive(waste)
 		}
-		runtime.KeepAlive(waste)
 	}
 
 	runSubtestProcess := func(t *testing.T) {
@@ -89,3 +98,373 @@ func TestStringMutations(t *testing.T) {
 	t.Run("AsString", runSubtestProcess)
 	t.Run("OfString", runSubtestProcess)
 }
+
+// TestCheckNowStringMutations verifies that CheckNow detects the same
+// mutations as TestStringMutations without waiting for the garbage collector.
+func TestCheckNowStringMutations(t *testing.T) {
+	// Keep the checks pending (and their finalizers from racing with the
+	// mutations below) until the data has been restored.
+	unblock := eventually.Block()
+	defer unblock()
+	unsafeslice.SetEagerRaceCheck(false)
+	defer unsafeslice.SetEagerRaceCheck(true)
+
+	var got []uintptr
+	unsafeslice.OnMutation = func(addr uintptr) { got = append(got, addr) }
+	defer func() { unsafeslice.OnMutation = nil }()
+
+	t.Run("AsString", func(t *testing.T) {
+		got = nil
+		b := []byte("Hello, world!")
+		_ = unsafeslice.AsString(b)
+		copy(b, "Kaboom")
+		defer copy(b, "Hello,") // Restore b so that later checks pass.
+
+		unsafeslice.CheckNow()
+		if len(got) == 0 {
+			t.Errorf("CheckNow failed to detect a mutation of the argument to AsString.")
+		}
+	})
+
+	t.Run("OfString", func(t *testing.T) {
+		got = nil
+		// As in TestStringMutations, avoid AsString so that its own check does not
+		// detect the mutation instead.
+		buf := []byte("Hello, world!")
+		var s string
+		hdr := (*reflect.StringHeader)(unsafe.Pointer(&s))
+		hdr.Data = uintptr(unsafe.Pointer(&buf[0]))
+		hdr.Len = len(buf)
+
+		b := unsafeslice.OfString(s)
+		copy(b, "Kaboom")
+		defer copy(b, "Hello,")
+
+		unsafeslice.CheckNow()
+		if want := uintptr(unsafe.Pointer(&b[0])); len(got) != 1 || got[0] != want {
+			t.Errorf("OnMutation called with %#x; want [%#x]", got, want)
+		}
+	})
+}
+
+// TestCheckedDataCollected verifies that the pending checks do not keep the
+// checked data alive after their finalizers have run.
+func TestCheckedDataCollected(t *testing.T) {
+	if paranoidEnabled {
+		t.Skip("paranoid checks keep the checked data until their last collection")
+	}
+
+	collected := make(chan struct{})
+	b := make([]byte, 64)
+	runtime.SetFinalizer(&b[0], func(*byte) { close(collected) })
+	_ = unsafeslice.AsString(b)
+	b = nil
+
+	for i := 0; i < 100; i++ {
+		runtime.GC()
+		select {
+		case <-collected:
+			return
+		case <-time.After(time.Millisecond):
+		}
+	}
+	t.Errorf("data checked by AsString was not collected after 100 garbage collections")
+}
+
+func TestSetMutationHash(t *testing.T) {
+	var calls int32
+	unsafeslice.SetMutationHash(func() hash.Hash64 {
+		atomic.AddInt32(&calls, 1)
+		return fnv.New64()
+	})
+	defer unsafeslice.SetMutationHash(nil)
+
+	t.Run("OfString", func(t *testing.T) {
+		if paranoidEnabled {
+			t.Skip("OfString copies its argument in paranoid mode instead of computing a checksum")
+		}
+		atomic.StoreInt32(&calls, 0)
+		_ = unsafeslice.OfString("Hello, world!")
+		if atomic.LoadInt32(&calls) == 0 {
+			t.Errorf("OfString did not use the hash set by SetMutationHash.")
+		}
+	})
+
+	t.Run("ConvertAtReadOnly", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		var b []byte
+		unsafeslice.ConvertAtReadOnly(&b, []byte("Hello, world!"))
+		if atomic.LoadInt32(&calls) == 0 {
+			t.Errorf("ConvertAtReadOnly did not use the hash set by SetMutationHash.")
+		}
+	})
+}
+
+func TestSetMutationChecksEnabled(t *testing.T) {
+	unsafeslice.SetMutationChecksEnabled(false)
+	defer unsafeslice.SetMutationChecksEnabled(true)
+
+	if unsafeslice.SafetyChecksEnabled() {
+		t.Errorf("SafetyChecksEnabled() = true after SetMutationChecksEnabled(false)")
+	}
+
+	s := "Hello, world!"
+	var b []byte
+	avg := testing.AllocsPerRun(1000, func() {
+		b = unsafeslice.OfString(s)
+	})
+	runtime.KeepAlive(b)
+	if avg > 0 {
+		t.Errorf("unsafeslice.OfString made %v allocations with checks disabled; want 0", avg)
+	}
+}
+
+func TestSetEagerRaceCheck(t *testing.T) {
+	if !raceEnabled {
+		t.Skip("eager checks are made only under the race detector")
+	}
+	if paranoidEnabled {
+		t.Skip("OfString copies its argument in paranoid mode, so it makes no recheck")
+	}
+
+	// Block finalizers, so that each check computes a checksum when it starts
+	// and then again only if it makes an eager recheck.
+	unblock := eventually.Block()
+	defer unblock()
+
+	var calls int32
+	unsafeslice.SetMutationHash(func() hash.Hash64 {
+		atomic.AddInt32(&calls, 1)
+		return fnv.New64()
+	})
+	defer unsafeslice.SetMutationHash(nil)
+
+	s := string([]byte("Hello, world!"))
+
+	unsafeslice.SetEagerRaceCheck(false)
+	defer unsafeslice.SetEagerRaceCheck(true)
+	_ = unsafeslice.OfString(s)
+	time.Sleep(10 * time.Millisecond)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("OfString computed %d checksums with eager checks disabled; want 1", n)
+	}
+
+	unsafeslice.SetEagerRaceCheck(true)
+	_ = unsafeslice.OfString(s)
+	for i := 0; atomic.LoadInt32(&calls) < 3 && i < 1000; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&calls) - 1; n != 2 {
+		t.Errorf("OfString computed %d checksums with eager checks enabled; want 2", n)
+	}
+}
+
+func TestOfStringScoped(t *testing.T) {
+	var got []uintptr
+	unsafeslice.OnMutation = func(addr uintptr) { got = append(got, addr) }
+	defer func() { unsafeslice.OnMutation = nil }()
+
+	before := unsafeslice.PendingCheckCount()
+	s := string([]byte("Hello, world!"))
+	b, done := unsafeslice.OfStringScoped(s)
+	done()
+	if len(got) != 0 {
+		t.Fatalf("OnMutation called without mutation: %#x", got)
+	}
+	if n := unsafeslice.PendingCheckCount(); n > before {
+		t.Errorf("PendingCheckCount() = %v after done; want at most %v", n, before)
+	}
+
+	// As in TestStringMutations, avoid AsString so that its own check does not
+	// detect the mutation later.
+	buf := []byte("Hello, world!")
+	hdr := (*reflect.StringHeader)(unsafe.Pointer(&s))
+	hdr.Data = uintptr(unsafe.Pointer(&buf[0]))
+	hdr.Len = len(buf)
+
+	b, done = unsafeslice.OfStringScoped(s)
+	copy(b, "Kaboom")
+	done()
+	done()
+	if want := uintptr(unsafe.Pointer(&b[0])); len(got) != 1 || got[0] != want {
+		t.Errorf("OnMutation called with %#x; want [%#x]", got, want)
+	}
+}
+
+func TestSetRecheckObserver(t *testing.T) {
+	if paranoidEnabled {
+		t.Skip("OfStringScoped copies its argument in paranoid mode, so it makes no recheck")
+	}
+
+	// Checks that started before the observer was set are not observed, so the
+	// only call should be for the check started below.
+	var (
+		mu      sync.Mutex
+		windows []time.Duration
+	)
+	unsafeslice.SetRecheckObserver(func(started, rechecked time.Time) {
+		mu.Lock()
+		windows = append(windows, rechecked.Sub(started))
+		mu.Unlock()
+	})
+	defer unsafeslice.SetRecheckObserver(nil)
+
+	_, done := unsafeslice.OfStringScoped(string([]byte("Hello, world!")))
+	time.Sleep(time.Millisecond)
+	done()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(windows) != 1 || windows[0] < time.Millisecond {
+		t.Errorf("observer called with windows %v; want one window of at least 1ms", windows)
+	}
+}
+
+// TestSetRecheckObserverFinalizer verifies that the observer reports the
+// window of a check that is rechecked by its finalizer.
+func TestSetRecheckObserverFinalizer(t *testing.T) {
+	if paranoidEnabled {
+		t.Skip("OfString copies its argument in paranoid mode, so it makes no recheck")
+	}
+
+	observed := make(chan time.Duration, 1)
+	unsafeslice.SetRecheckObserver(func(started, rechecked time.Time) {
+		select {
+		case observed <- rechecked.Sub(started):
+		default:
+		}
+	})
+	defer unsafeslice.SetRecheckObserver(nil)
+
+	_ = unsafeslice.OfString(string([]byte("Hello, world!")))
+
+	for i := 0; i < 100; i++ {
+		runtime.GC()
+		select {
+		case window := <-observed:
+			if window < 0 {
+				t.Errorf("observer called with negative window %v", window)
+			}
+			return
+		case <-time.After(time.Millisecond):
+		}
+	}
+	t.Errorf("observer not called after 100 garbage collections")
+}
+
+func TestStartBackgroundChecker(t *testing.T) {
+	if raceEnabled {
+		// The mutation below would be reported as a data race, failing the test.
+		// TestBackgroundCheckerRace covers the race detector instead.
+		t.Skip("mutations are data races under the race detector")
+	}
+
+	stop := unsafeslice.StartBackgroundChecker(time.Millisecond)
+	defer stop()
+
+	detected := make(chan uintptr, 1)
+	unsafeslice.OnMutation = func(addr uintptr) {
+		select {
+		case detected <- addr:
+		default:
+		}
+	}
+	defer func() { unsafeslice.OnMutation = nil }()
+
+	b := unsafeslice.Freeze([]byte("Hello, world!"))
+	defer runtime.KeepAlive(b)
+	copy(b, "Kaboom")
+	defer copy(b, "Hello,") // Restore b so that later checks pass.
+	defer stop()            // Stop sweeping before restoring b or OnMutation.
+
+	select {
+	case addr := <-detected:
+		if want := uintptr(unsafe.Pointer(&b[0])); addr != want {
+			t.Errorf("OnMutation called with %#x; want %#x", addr, want)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("background checker did not detect mutation within 10s")
+	}
+}
+
+// TestConvertAtOfString verifies that the mutation checks started by OfString
+// detect writes through a slice converted from its result.
+func TestConvertAtOfString(t *testing.T) {
+	unsafeslice.SetEagerRaceCheck(false)
+	defer unsafeslice.SetEagerRaceCheck(true)
+
+	// Back the string with aligned, mutable memory, so that the conversion
+	// succeeds and the write below does not fault.
+	buf := make([]uint32, 4)
+	var bufBytes []byte
+	unsafeslice.ConvertAt(&bufBytes, buf)
+	copy(bufBytes, "Hello, world!!!!")
+	var s string
+	hdr := (*reflect.StringHeader)(unsafe.Pointer(&s))
+	hdr.Data = uintptr(unsafe.Pointer(&buf[0]))
+	hdr.Len = len(bufBytes)
+
+	var u32 []uint32
+	unsafeslice.ConvertAt(&u32, unsafeslice.OfString(s))
+	u32[0] ^= 1
+	defer func() { u32[0] ^= 1 }() // Restore the data so that later checks pass.
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("CheckNow failed to detect a write through a slice converted from OfString.")
+		}
+	}()
+	unsafeslice.CheckNow()
+}
+
+func TestDumpPendingCheckers(t *testing.T) {
+	unsafeslice.SetCheckerDebug(true)
+	defer unsafeslice.SetCheckerDebug(false)
+
+	b := unsafeslice.Freeze([]byte("Hello, world!"))
+	defer runtime.KeepAlive(b)
+
+	var out bytes.Buffer
+	if err := unsafeslice.DumpPendingCheckers(&out); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("DumpPendingCheckers:\n%s", &out)
+
+	if !strings.HasSuffix(strings.SplitN(out.String(), "\n", 2)[0], " pending mutation checks") {
+		t.Errorf("DumpPendingCheckers output does not begin with the number of pending checks.")
+	}
+	if want := "unsafeslice_test.TestDumpPendingCheckers"; !strings.Contains(out.String(), want) {
+		t.Errorf("DumpPendingCheckers output does not mention %s.", want)
+	}
+	if strings.Contains(out.String(), "unsafeslice.Freeze") {
+		t.Errorf("DumpPendingCheckers output includes frames from package unsafeslice.")
+	}
+}
+
+func TestKeyOf(t *testing.T) {
+	unsafeslice.SetEagerRaceCheck(false)
+	defer unsafeslice.SetEagerRaceCheck(true)
+
+	b := []byte("Hello, world!")
+	m := map[string]int{unsafeslice.KeyOf(b): 1}
+	if m["Hello, world!"] != 1 {
+		t.Fatalf("map keyed by KeyOf(%q) does not contain %q", b, b)
+	}
+
+	copy(b, "Kaboom")
+	defer copy(b, "Hello,") // Restore b so that later checks pass.
+
+	defer func() {
+		msg := recover()
+		if msg == nil {
+			t.Fatalf("CheckNow failed to detect a mutation of a key from KeyOf.")
+		}
+		t.Logf("recovered: %v", msg)
+		if want := "unsafeslice_test.TestKeyOf"; !paranoidEnabled && !strings.Contains(fmt.Sprint(msg), want) {
+			t.Errorf("panic message does not mention %s.", want)
+		}
+	}()
+	unsafeslice.CheckNow()
+}
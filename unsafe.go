@@ -2,13 +2,112 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// +build unsafe,!race
+//go:build unsafe && !race && !paranoid
+// +build unsafe,!race,!paranoid
 
 package unsafeslice
 
+import (
+	"fmt"
+	"hash"
+	"io"
+	"time"
+	"unsafe"
+)
+
 // This file contains declarations for “extra unsafe” mode,
 // which disables mutation checks for string functions.
 
+// SafetyChecksEnabled reports whether OfString, AsString, and related
+// functions make a best-effort attempt to detect mutations.
+//
+// It returns false if the program was built with the "unsafe" tag and without
+// the race detector, or if checks have been disabled by
+// SetMutationChecksEnabled.
+func SafetyChecksEnabled() bool {
+	return false
+}
+
+// SetMutationChecksEnabled enables or disables mutation checks for subsequent
+// calls to OfString, AsString, and related functions. Since this build makes
+// no mutation checks, SetMutationChecksEnabled has no effect.
+func SetMutationChecksEnabled(enabled bool) {}
+
+// SetEagerRaceCheck enables or disables the goroutine that OfString, AsString,
+// and related functions start to re-read each checked slice when the race
+// detector is enabled. Since this build makes no mutation checks,
+// SetEagerRaceCheck has no effect.
+func SetEagerRaceCheck(enabled bool) {}
+
+// OnMutation, if non-nil, is called with the address of the mutated data
+// instead of panicking when a mutation check fails. Since this build makes no
+// mutation checks, OnMutation is never called.
+var OnMutation func(addr uintptr)
+
+// SetMutationHash sets the function used to construct the hash with which
+// mutation checks compute checksums. Since this build makes no mutation
+// checks, SetMutationHash has no effect.
+func SetMutationHash(newHash func() hash.Hash64) {}
+
+// SetRecheckObserver sets a function to be called each time a mutation check
+// makes its final recheck. Since this build makes no mutation checks, f is
+// never called.
+func SetRecheckObserver(f func(started, rechecked time.Time)) {}
+
+// StartBackgroundChecker starts a goroutine that periodically repeats every
+// pending mutation check. Since this build makes no mutation checks,
+// StartBackgroundChecker starts nothing, and stop has no effect.
+func StartBackgroundChecker(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		panic(fmt.Sprintf("StartBackgroundChecker with non-positive interval %v", interval))
+	}
+	return func() {}
+}
+
+// CheckNow synchronously repeats every pending mutation check. Since this build
+// makes no mutation checks, CheckNow has no effect.
+func CheckNow() {}
+
+// PendingCheckCount returns the number of mutation checks that CheckNow would
+// currently repeat. Since this build makes no mutation checks, it always
+// returns 0.
+func PendingCheckCount() int {
+	return 0
+}
+
+// SetCheckerDebug enables or disables recording the call stack that starts
+// each mutation check. Since this build makes no mutation checks,
+// SetCheckerDebug has no effect.
+func SetCheckerDebug(enabled bool) {}
+
+// DumpPendingCheckers writes to w the number of pending mutation checks and
+// the call sites that started them. Since this build makes no mutation checks,
+// it reports that none are pending.
+func DumpPendingCheckers(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "0 pending mutation checks\n")
+	return err
+}
+
 // maybeDetectMutations makes no attempt whatsoever to detect mutations and
 // lifetime errors on the passed-in slice.
 func maybeDetectMutations([]byte) {}
+
+// maybeDetectMutationsAll makes no attempt whatsoever to detect mutations and
+// lifetime errors on the passed-in slices.
+func maybeDetectMutationsAll([][]byte) {}
+
+// maybeDetectMutationsScoped makes no attempt whatsoever to detect mutations
+// and lifetime errors on the passed-in slice, and returns a function that does
+// nothing.
+func maybeDetectMutationsScoped([]byte) (done func()) { return func() {} }
+
+// checkWritable has no pending checks against which to check the n bytes
+// starting at p.
+func checkWritable(op string, p unsafe.Pointer, n uintptr) {}
+
+// releaseMutationChecks has no pending checks to release.
+func releaseMutationChecks(p unsafe.Pointer, n uintptr) {}
+
+// maybeDetectKeyMutations makes no attempt whatsoever to detect mutations and
+// lifetime errors on the passed-in slice.
+func maybeDetectKeyMutations([]byte) {}
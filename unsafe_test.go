@@ -2,8 +2,11 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// +build unsafe,!race
+//go:build unsafe && !race && !paranoid
+// +build unsafe,!race,!paranoid
 
 package unsafeslice_test
 
+const safetyChecksEnabled = false
+
 const maxStringAllocs = 0
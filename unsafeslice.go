@@ -8,6 +8,7 @@ package unsafeslice
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"unsafe"
 )
 
@@ -16,18 +17,36 @@ import (
 //
 // The caller must ensure that p meets the alignment requirements for dst, and
 // that the allocation to which p points contains at least n contiguous
-// elements.
+// elements. SetAt panics if n is negative, and sets *dst to nil if n is zero.
 //
 // This implements one possible API for https://golang.org/issue/19367
 // and https://golang.org/issue/13656.
 //
-// Deprecated: as of Go 1.17, use unsafe.Slice instead.
+// Deprecated: as of Go 1.18, use SliceAt instead.
 func SetAt(dst interface{}, p unsafe.Pointer, n int) {
+	setAt("SetAt", dst, p, n, n)
+}
+
+// SetAtCap is like SetAt, but sets dst to a slice of length n and capacity m,
+// so that the slice may later be extended up to m elements. SetAtCap panics
+// unless 0 <= n <= m, and sets *dst to nil if m is zero.
+//
+// The caller must ensure that the allocation to which p points contains at
+// least m contiguous elements.
+func SetAtCap(dst interface{}, p unsafe.Pointer, n, m int) {
+	setAt("SetAtCap", dst, p, n, m)
+}
+
+// setAt implements SetAt and SetAtCap, using op as the name of the operation
+// in panics.
+func setAt(op string, dst interface{}, p unsafe.Pointer, n, m int) {
 	dv := reflect.ValueOf(dst)
 	dt := dv.Type()
 	if dt.Kind() != reflect.Ptr || dt.Elem().Kind() != reflect.Slice {
-		panic(fmt.Sprintf("SetAt with dst type %T; need *[]T", dst))
+		panic(fmt.Sprintf("%s with dst type %T; need *[]T", op, dst))
 	}
+	checkLenCap(op, n, m)
+	checkAddrRange(op, p, m, dt.Elem().Elem().Size())
 
 	hdr := (*reflect.SliceHeader)(unsafe.Pointer(dv.Pointer()))
 
@@ -35,56 +54,363 @@ func SetAt(dst interface{}, p unsafe.Pointer, n int) {
 	// invalid slice.
 	hdr.Len = 0
 	hdr.Cap = 0
+	if m == 0 {
+		// An empty slice need not carry a valid pointer, so leave *dst nil
+		// rather than retaining a possibly-bogus p.
+		hdr.Data = 0
+		return
+	}
 
 	// Now set the slice to point to p, then expand the cap and length,
 	// again ensuring that the slice is always valid.
 	hdr.Data = uintptr(p)
-	hdr.Cap = n
+	hdr.Cap = m
 	hdr.Len = n
 }
 
+// checkLenCap panics unless 0 <= n <= m.
+func checkLenCap(op string, n, m int) {
+	if n < 0 {
+		panic(fmt.Sprintf("%s with negative length %d", op, n))
+	}
+	if m < n {
+		panic(fmt.Sprintf("%s with capacity %d less than length %d", op, m, n))
+	}
+}
+
+// checkAddrRange panics if n elements of size elemSize starting at p would
+// extend past the end of the address space.
+func checkAddrRange(op string, p unsafe.Pointer, n int, elemSize uintptr) {
+	if !inAddrRange(p, n, elemSize) {
+		panic(&addrRangeError{op, uintptr(p), n, elemSize})
+	}
+}
+
+// inAddrRange reports whether n elements of size elemSize starting at p end
+// within the address space. n must be non-negative.
+func inAddrRange(p unsafe.Pointer, n int, elemSize uintptr) bool {
+	return elemSize == 0 || uintptr(n) <= (^uintptr(0)-uintptr(p))/elemSize
+}
+
+// An addrRangeError is the value with which checkAddrRange, and SetSliceAt for
+// a negative n, panic. It defers formatting its message until it is printed or
+// recovered, so that checkAddrRange, and the functions that call it, remain
+// small enough to inline.
+type addrRangeError struct {
+	op       string
+	addr     uintptr
+	n        int
+	elemSize uintptr
+}
+
+func (e *addrRangeError) Error() string {
+	if e.n < 0 {
+		return fmt.Sprintf("%s with negative length %d", e.op, e.n)
+	}
+	return fmt.Sprintf("%s: %d elements of %d bytes at %#x overflow the address space", e.op, e.n, e.elemSize, e.addr)
+}
+
+// AlignedAt reports whether p meets the alignment requirements for the
+// elements of dst, which must be a pointer to a variable of a slice type.
+//
+// AlignedAt can be used to check the alignment precondition of SetAt and
+// ConvertAt before calling them.
+func AlignedAt(dst interface{}, p unsafe.Pointer) bool {
+	dt := reflect.TypeOf(dst)
+	if dt == nil || dt.Kind() != reflect.Ptr || dt.Elem().Kind() != reflect.Slice {
+		panic(fmt.Sprintf("AlignedAt with dst type %T; need *[]T", dst))
+	}
+	return uintptr(p)%uintptr(dt.Elem().Elem().Align()) == 0
+}
+
 // ConvertAt sets dst, which must be a non-nil pointer to a variable of a slice
 // type, to a slice that refers to the same memory region as the slice src,
 // but possibly at a different type.
 //
 // The caller must ensure that src meets the alignment requirements for dst, and
 // that the length and capacity of src are integer multiples of the element size
-// of dst.
+// of dst. ConvertAt panics if either requirement is not met, or if the element
+// type of dst contains pointers and that of src does not have the same size
+// and pointers at the same offsets. As for ConvertTo, a result with capacity
+// zero need not be aligned.
+//
+// dst may instead be a non-nil pointer to a variable of an array type [N]T, in
+// which case the length of src in bytes must equal the size of the array. An
+// array variable cannot refer to other memory, so ConvertAt instead copies the
+// bytes of src into *dst; alignment and the capacity of src do not matter.
+//
+// Converting a []byte to a []rune reinterprets each group of four bytes as a
+// single int32 value; it does not decode UTF-8. Since rune is an alias for
+// int32, ConvertAt cannot tell the two apart. To decode text, use
+// []rune(string(b)) instead.
+//
+// If src refers to the data of a string, as from OfString, so does *dst: the
+// caller must not write through it, since a string constant may reside in
+// read-only memory. The mutation checks that cover src also cover *dst,
+// because they track the memory itself rather than a particular slice.
 //
 // This implements one possible API for https://golang.org/issue/38203.
 func ConvertAt(dst, src interface{}) {
+	if err := convertAtOrArray("ConvertAt", dst, src); err != nil {
+		panic(err.Error())
+	}
+}
+
+// ConvertAtReadOnly is like ConvertAt, but additionally applies the same
+// mutation checks as OfString to the resulting slice.
+//
+// The caller must ensure that the memory shared by dst and src is never again
+// mutated, through either slice or any other alias.
+//
+// Unlike ConvertAt, ConvertAtReadOnly does not accept a pointer to an array
+// variable as dst: an array would hold a copy of src, with no memory shared to
+// check.
+func ConvertAtReadOnly(dst, src interface{}) {
+	if err := convertAt("ConvertAtReadOnly", dst, src); err != nil {
+		panic(err.Error())
+	}
+
+	dv := reflect.ValueOf(dst).Elem()
+	var b []byte
+	SetAt(&b, unsafe.Pointer(dv.Pointer()), dv.Len()*int(dv.Type().Elem().Size()))
+	maybeDetectMutations(b)
+}
+
+// TryConvertAt is like ConvertAt, but returns an error instead of panicking if
+// src cannot be represented as a slice of the element type of dst: a
+// *ConversionError if the length or capacity of src does not fit, or an
+// *AlignmentError if src is not suitably aligned. If TryConvertAt returns a
+// non-nil error, *dst is left unmodified.
+//
+// Like ConvertAt, TryConvertAt also accepts a pointer to an array variable as
+// dst.
+//
+// TryConvertAt still panics if dst or src is not of the required type, since
+// that is a mistake in the program rather than in its input data.
+func TryConvertAt(dst, src interface{}) error {
+	return convertAtOrArray("TryConvertAt", dst, src)
+}
+
+// ConvertAtLen is like ConvertAt, but ignores any capacity of src beyond its
+// length: it requires only the length of src to be a multiple of the size of
+// the element type of dst, and sets *dst to a slice whose capacity equals its
+// length. It is useful for subslices of larger buffers whose capacity is not a
+// multiple of that size.
+//
+// Unlike ConvertAt, ConvertAtLen does not accept a pointer to an array variable
+// as dst; ConvertAt itself ignores the capacity of src in that case.
+func ConvertAtLen(dst, src interface{}) {
+	sv := reflect.ValueOf(src)
+	if sv.Kind() != reflect.Slice {
+		panic(fmt.Sprintf("ConvertAtLen with src type %T; need []T", src))
+	}
+	n := sv.Len()
+	err := convertAt("ConvertAtLen", dst, sv.Slice3(0, n, n).Interface())
+	if cerr, ok := err.(*ConversionError); ok {
+		// The capacity passed to convertAt is the length of src, so report it as
+		// such.
+		cerr.Field = "length"
+	}
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+// convertAtOrArray implements ConvertAt and TryConvertAt, which accept either
+// a *[]T or a *[N]T as dst.
+func convertAtOrArray(op string, dst, src interface{}) error {
+	dt := reflect.TypeOf(dst)
+	if dt == nil || dt.Kind() != reflect.Ptr || (dt.Elem().Kind() != reflect.Slice && dt.Elem().Kind() != reflect.Array) {
+		panic(fmt.Sprintf("%s with dst type %T; need *[]T or *[N]T", op, dst))
+	}
+	if dt.Elem().Kind() == reflect.Slice {
+		return convertAt(op, dst, src)
+	}
+
+	sv := reflect.ValueOf(src)
+	st := sv.Type()
+	if st.Kind() != reflect.Slice {
+		panic(fmt.Sprintf("%s with src type %T; need []T", op, src))
+	}
+	at := dt.Elem()
+	if containsPointers(at) && !containsPointers(st.Elem()) {
+		panic(fmt.Sprintf("%s: dst type %v contains pointers; reinterpretation would corrupt the heap", op, at))
+	}
+
+	lenBytes := uintptr(sv.Len()) * st.Elem().Size()
+	if lenBytes != at.Size() {
+		return &ConversionError{Op: op, Field: "array", SrcBytes: lenBytes, DstElem: at, DstElemSize: at.Size()}
+	}
+	if lenBytes == 0 {
+		return nil
+	}
+
+	var to, from []byte
+	SetAt(&to, unsafe.Pointer(reflect.ValueOf(dst).Pointer()), int(lenBytes))
+	SetAt(&from, unsafe.Pointer(sv.Pointer()), int(lenBytes))
+	copy(to, from)
+	return nil
+}
+
+// ConvertAtOrCopy is like ConvertAt, but if src is not suitably aligned for
+// the element type of dst, or its capacity is not a multiple of that type's
+// size, ConvertAtOrCopy sets dst to a newly-allocated copy of the bytes of src
+// instead of panicking. It reports whether it made a copy: if so, writes
+// through dst are not visible through src, and vice versa.
+//
+// ConvertAtOrCopy still panics if the length of src is not a multiple of the
+// size of the element type of dst, or if dst or src is not of the required
+// type. Unlike ConvertAt, it does not accept a pointer to an array variable as
+// dst, since ConvertAt always copies into an array anyway.
+func ConvertAtOrCopy(dst, src interface{}) (copied bool) {
+	err := convertAt("ConvertAtOrCopy", dst, src)
+	if err == nil {
+		return false
+	}
+
+	sv := reflect.ValueOf(src)
+	dstElem := reflect.TypeOf(dst).Elem().Elem()
+	lenBytes := uintptr(sv.Len()) * sv.Type().Elem().Size()
+	n, ok := elemCount(lenBytes, dstElem.Size())
+	if !ok {
+		err := &ConversionError{Op: "ConvertAtOrCopy", Field: "length", SrcBytes: lenBytes, DstElem: dstElem, DstElemSize: dstElem.Size()}
+		panic(err.Error())
+	}
+
+	fresh := reflect.MakeSlice(reflect.SliceOf(dstElem), n, n)
+	if n > 0 {
+		var to, from []byte
+		SetAt(&to, unsafe.Pointer(fresh.Pointer()), int(lenBytes))
+		SetAt(&from, unsafe.Pointer(sv.Pointer()), int(lenBytes))
+		copy(to, from)
+	}
+	reflect.ValueOf(dst).Elem().Set(fresh)
+	return true
+}
+
+// ConvertBytes computes the header of a slice that refers to the same memory as
+// src, but with elements of dstElemSize bytes, for callers that know the
+// element size only at run time. The caller can then construct the slice from
+// the returned words, for example with SetAtCap.
+//
+// ConvertBytes returns a *ConversionError, with a nil DstElem, if the length
+// or capacity of src is not a multiple of dstElemSize. It does not check the
+// alignment of src, which depends on the element type: the caller must ensure
+// that data is suitably aligned for whatever type it stores there. ConvertBytes
+// panics if dstElemSize is not positive.
+func ConvertBytes(src []byte, dstElemSize int) (length, capacity int, data unsafe.Pointer, err error) {
+	if dstElemSize <= 0 {
+		panic(fmt.Sprintf("ConvertBytes with non-positive dst element size %d", dstElemSize))
+	}
+	size := uintptr(dstElemSize)
+
+	capacity, ok := elemCount(uintptr(cap(src)), size)
+	if !ok {
+		return 0, 0, nil, &ConversionError{Op: "ConvertBytes", Field: "capacity", SrcBytes: uintptr(cap(src)), DstElemSize: size}
+	}
+	length, ok = elemCount(uintptr(len(src)), size)
+	if !ok {
+		return 0, 0, nil, &ConversionError{Op: "ConvertBytes", Field: "length", SrcBytes: uintptr(len(src)), DstElemSize: size}
+	}
+	data = unsafe.Pointer((*reflect.SliceHeader)(unsafe.Pointer(&src)).Data)
+	return length, capacity, data, nil
+}
+
+// A ConversionError reports that the length or capacity of a slice cannot be
+// converted to a whole number of destination elements, or that the destination
+// elements have size zero.
+//
+// For a conversion to an array, Field is "array", DstElem is the array type,
+// and the length of src in bytes must equal DstElemSize exactly. For
+// ConvertBytes, which has only an element size, DstElem is nil.
+type ConversionError struct {
+	Op          string       // the failing operation, such as "ConvertAt"
+	Field       string       // "length", "capacity", or "array"
+	SrcBytes    uintptr      // the length or capacity of src, in bytes
+	DstElem     reflect.Type // the element type of dst
+	DstElemSize uintptr      // the size of DstElem, in bytes
+}
+
+func (e *ConversionError) Error() string {
+	size := fmt.Sprintf("%d bytes", e.DstElemSize)
+	if e.DstElem != nil {
+		size = fmt.Sprintf("%v: %s", e.DstElem, size)
+	}
+	if e.Field == "array" {
+		return fmt.Sprintf("%s: src length (%d bytes) does not match dst array size (%s)", e.Op, e.SrcBytes, size)
+	}
+	if e.DstElemSize == 0 {
+		return fmt.Sprintf("%s: dst element size (%s) is zero", e.Op, size)
+	}
+	if e.SrcBytes%e.DstElemSize == 0 {
+		return fmt.Sprintf("%s: dst %s (%d) overflows int", e.Op, e.Field, e.SrcBytes/e.DstElemSize)
+	}
+	return fmt.Sprintf("%s: src %s (%d bytes) is not a multiple of dst element size (%s)", e.Op, e.Field, e.SrcBytes, size)
+}
+
+// An AlignmentError reports that the data of a slice is not aligned for the
+// element type of the slice to which it is being converted.
+type AlignmentError struct {
+	Op    string       // the failing operation, such as "ConvertAt"
+	Addr  uintptr      // the address of the src data
+	Dst   reflect.Type // the slice type of dst
+	Align uintptr      // the required alignment, in bytes
+}
+
+func (e *AlignmentError) Error() string {
+	return fmt.Sprintf("%s: src data at 0x%x is not aligned for %v (need %d-byte alignment)", e.Op, e.Addr, e.Dst, e.Align)
+}
+
+// convertAt implements ConvertAt and TryConvertAt, using op as the name of
+// the operation in panics and errors.
+func convertAt(op string, dst, src interface{}) error {
 	sv := reflect.ValueOf(src)
 	st := sv.Type()
 	if st.Kind() != reflect.Slice {
-		panic(fmt.Sprintf("ConvertAt with src type %T; need []T", src))
+		panic(fmt.Sprintf("%s with src type %T; need []T", op, src))
 	}
 
 	dv := reflect.ValueOf(dst)
 	dt := dv.Type()
 	if dt.Kind() != reflect.Ptr || dt.Elem().Kind() != reflect.Slice {
-		panic(fmt.Sprintf("ConvertAt with dst type %T; need *[]T", dst))
+		panic(fmt.Sprintf("%s with dst type %T; need *[]T", op, dst))
+	}
+
+	dstElem := dt.Elem().Elem()
+	if containsPointers(dstElem) {
+		srcElem := st.Elem()
+		if !containsPointers(srcElem) {
+			// The garbage collector would interpret arbitrary bytes from src as
+			// pointers.
+			panic(fmt.Sprintf("%s: dst element type %v contains pointers; reinterpretation would corrupt the heap", op, dstElem))
+		}
+		// As for ReinterpretPtrSlice, every pointer in dst must also be a
+		// pointer in src, and vice-versa.
+		if dstElem.Size() != srcElem.Size() || !reflect.DeepEqual(appendPointerOffsets(nil, dstElem, 0), appendPointerOffsets(nil, srcElem, 0)) {
+			panic(fmt.Sprintf("%s from %v to %v, which have different pointer layouts; reinterpretation would corrupt the heap", op, srcElem, dstElem))
+		}
 	}
 
 	srcElemSize := st.Elem().Size()
 	capBytes := uintptr(sv.Cap()) * srcElemSize
 	lenBytes := uintptr(sv.Len()) * srcElemSize
 
-	dstElemSize := dt.Elem().Elem().Size()
+	dstElemSize := dstElem.Size()
 
-	if capBytes%dstElemSize != 0 {
-		panic(fmt.Sprintf("ConvertAt: src capacity (%d bytes) is not a multiple of dst element size (%v: %d bytes)", capBytes, dt.Elem().Elem(), dstElemSize))
+	dstCap, ok := elemCount(capBytes, dstElemSize)
+	if !ok {
+		return &ConversionError{Op: op, Field: "capacity", SrcBytes: capBytes, DstElem: dstElem, DstElemSize: dstElemSize}
 	}
-	dstCap := capBytes / dstElemSize
-	if int(dstCap) < 0 || uintptr(int(dstCap)) != dstCap {
-		panic(fmt.Sprintf("ConvertAt: dst capacity (%d) overflows int", dstCap))
+	dstLen, ok := elemCount(lenBytes, dstElemSize)
+	if !ok {
+		return &ConversionError{Op: op, Field: "length", SrcBytes: lenBytes, DstElem: dstElem, DstElemSize: dstElemSize}
 	}
 
-	if lenBytes%dstElemSize != 0 {
-		panic(fmt.Sprintf("ConvertAt: src length (%d bytes) is not a multiple of dst element size (%v: %d bytes)", lenBytes, dt.Elem().Elem(), dstElemSize))
-	}
-	dstLen := lenBytes / dstElemSize
-	if int(dstLen) < 0 || uintptr(int(dstLen)) != dstLen {
-		panic(fmt.Sprintf("ConvertAt: dst length (%d) overflows int", dstLen))
+	// A result with no capacity never accesses its data, so it need not be
+	// aligned.
+	data := sv.Pointer()
+	if align := uintptr(dstElem.Align()); dstCap != 0 && data%align != 0 {
+		return &AlignmentError{Op: op, Addr: data, Dst: dt.Elem(), Align: align}
 	}
 
 	hdr := (*reflect.SliceHeader)(unsafe.Pointer(dv.Pointer()))
@@ -96,58 +422,205 @@ func ConvertAt(dst, src interface{}) {
 
 	// Now set the slice to point to src, then expand the cap and length,
 	// again ensuring that the slice is always valid.
-	hdr.Data = uintptr(unsafe.Pointer(sv.Pointer()))
-	hdr.Cap = int(dstCap)
-	hdr.Len = int(dstLen)
+	hdr.Data = data
+	hdr.Cap = dstCap
+	hdr.Len = dstLen
+	return nil
+}
+
+// containsPointers reports whether values of type t include any words that the
+// garbage collector treats as pointers.
+func containsPointers(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.UnsafePointer, reflect.Map, reflect.Chan, reflect.Func,
+		reflect.Interface, reflect.Slice, reflect.String:
+		return true
+	case reflect.Array:
+		return t.Len() > 0 && containsPointers(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if containsPointers(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// appendPointerOffsets appends to offs the offsets, relative to base, of the
+// words within a value of type t that the garbage collector treats as pointers.
+func appendPointerOffsets(offs []uintptr, t reflect.Type, base uintptr) []uintptr {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.UnsafePointer, reflect.Map, reflect.Chan, reflect.Func,
+		reflect.String, reflect.Slice:
+		// Only the first word (the data pointer, for strings and slices) is a
+		// pointer.
+		return append(offs, base)
+	case reflect.Interface:
+		// Both the type word and the data word are treated as pointers.
+		return append(offs, base, base+unsafe.Sizeof(uintptr(0)))
+	case reflect.Array:
+		if !containsPointers(t) {
+			return offs
+		}
+		elemSize := t.Elem().Size()
+		for i := 0; i < t.Len(); i++ {
+			offs = appendPointerOffsets(offs, t.Elem(), base+uintptr(i)*elemSize)
+		}
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			offs = appendPointerOffsets(offs, f.Type, base+f.Offset)
+		}
+	}
+	return offs
+}
+
+// elemCount returns the number of elements of size elemSize in n bytes.
+// It reports false if elemSize is zero, if n is not a multiple of elemSize, or
+// if the number of elements overflows int.
+func elemCount(n, elemSize uintptr) (int, bool) {
+	if elemSize == 0 || n%elemSize != 0 {
+		return 0, false
+	}
+	count := n / elemSize
+	if int(count) < 0 || uintptr(int(count)) != count {
+		return 0, false
+	}
+	return int(count), true
 }
 
-// OfString returns a slice that refers to the data backing the string s.
+// OfStringAt returns a slice that refers to the bytes of s in the window
+// [i, j). It panics unless 0 <= i <= j <= len(s).
 //
-// The caller must ensure that the contents of the slice are never mutated.
+// OfStringAt is equivalent to OfString(s)[i:j], except that any mutation
+// checks cover only the window rather than all of s. The same requirements
+// apply as for OfString.
+func OfStringAt(s string, i, j int) []byte {
+	if i < 0 || j < i || j > len(s) {
+		panic(fmt.Sprintf("OfStringAt: window [%d:%d] out of range for string of length %d", i, j, len(s)))
+	}
+	return OfString(s[i:j])
+}
+
+// KeyOf is like AsString, but is intended for strings used as map keys, which
+// are silently corrupted if their contents change.
 //
-// Programs that use OfString should be tested under the race detector to flag
-// erroneous mutations.
+// KeyOf snapshots the contents of b and starts a mutation check immediately,
+// like AsString, but also records the call stack, so that if the check fails
+// its panic reports where the key was created. Recording the stack makes KeyOf
+// more expensive than AsString. The same requirements apply as for AsString.
+func KeyOf(b []byte) string {
+	if paranoidEnabled && SafetyChecksEnabled() {
+		return paranoidAsString(b)
+	}
+
+	s := bytesString(b)
+	maybeDetectKeyMutations(b)
+	return s
+}
+
+// StringHeader returns the two words of the header of s: the address of its
+// data and its length.
 //
-// Programs that have been adequately tested and shown to be safe may be
-// recompiled with the "unsafe" tag to significantly reduce the overhead of this
-// function, at the cost of reduced safety checks. Programs built under the race
-// detector always have safety checks enabled, even when the "unsafe" tag is
-// set.
-func OfString(s string) []byte {
-	p := unsafe.Pointer((*reflect.StringHeader)(unsafe.Pointer(&s)).Data)
+// StringHeader is intended for diagnostics and tests, such as asserting that
+// AsString returned a string that aliases the original slice. The caller must
+// not write through the returned address.
+func StringHeader(s string) (data uintptr, length int) {
+	return uintptr(DataOfString(s)), len(s)
+}
 
-	var b []byte
-	hdr := (*reflect.SliceHeader)(unsafe.Pointer(&b))
-	hdr.Data = uintptr(p)
-	hdr.Cap = len(s)
-	hdr.Len = len(s)
+// OfStringUnchecked is like OfString, but never makes mutation checks, even in
+// builds without the "unsafe" tag.
+//
+// OfStringUnchecked allows a call site that has been shown to be safe, and
+// whose performance matters, to opt out of the checks individually rather
+// than disabling them for the whole program. The same requirements apply as
+// for OfString.
+func OfStringUnchecked(s string) []byte {
+	return stringBytes(s)
+}
 
-	maybeDetectMutations(b)
-	return b
+// AsStringUnchecked is like AsString, but never makes mutation checks, even in
+// builds without the "unsafe" tag. The same requirements apply as for
+// AsString.
+func AsStringUnchecked(b []byte) string {
+	return bytesString(b)
 }
 
-// AsString returns a string that refers to the data backing the slice s.
+// OfStringScoped is like OfString, but also returns a function done that marks
+// the end of the window during which the caller uses b. done immediately
+// repeats the mutation check for b, panicking (or calling OnMutation) if b has
+// been mutated, and then discards the check.
 //
-// The caller must ensure that the contents of the slice are never again
-// mutated, and that its memory either is managed by the Go garbage collector or
-// remains valid for the remainder of this process's lifetime.
+// The caller must call done, typically in a defer statement, and must not use
+// b afterward. Calls to done after the first have no effect. Unlike OfString,
+// OfStringScoped makes no checks after done returns, so it detects mutations
+// deterministically but only within the window.
+func OfStringScoped(s string) (b []byte, done func()) {
+	if paranoidEnabled && SafetyChecksEnabled() {
+		return paranoidOfStringScoped(s)
+	}
+
+	b = stringBytes(s)
+	return b, maybeDetectMutationsScoped(b)
+}
+
+// OfStrings returns slices that refer to the data backing each of the strings
+// in ss, as if by calling OfString on each one.
 //
-// Programs that use AsString should be tested under the race detector to flag
-// erroneous mutations.
+// OfStrings amortizes the cost of mutation checks by covering all of the
+// returned slices with a single check, which makes it much cheaper than
+// calling OfString on each of many small strings. The same requirements apply
+// as for OfString.
+func OfStrings(ss []string) [][]byte {
+	bs := make([][]byte, len(ss))
+	if paranoidEnabled && SafetyChecksEnabled() {
+		for i, s := range ss {
+			bs[i] = paranoidOfString(s)
+		}
+		return bs
+	}
+
+	for i, s := range ss {
+		bs[i] = stringBytes(s)
+	}
+	maybeDetectMutationsAll(bs)
+	return bs
+}
+
+// OfStringBuilder returns a slice that refers to the bytes written to b so far,
+// without copying them, such as to hash the contents of b before it is
+// complete. It is equivalent to OfString(b.String()), and the same mutation
+// checks apply.
 //
-// Programs that have been adequately tested and shown to be safe may be
-// recompiled with the "unsafe" tag to significantly reduce the overhead of this
-// function, at the cost of reduced safety checks. Programs built under the race
-// detector always have safety checks enabled, even when the "unsafe" tag is
-// set.
-func AsString(b []byte) string {
-	p := unsafe.Pointer((*reflect.SliceHeader)(unsafe.Pointer(&b)).Data)
+// A strings.Builder never overwrites bytes that have already been written to
+// it: later writes either append after them or move the contents to a new
+// buffer. The returned slice therefore remains valid, but does not reflect
+// later writes. As with OfString, the caller must not mutate the slice.
+func OfStringBuilder(b *strings.Builder) []byte {
+	return OfString(b.String())
+}
 
-	var s string
-	hdr := (*reflect.StringHeader)(unsafe.Pointer(&s))
-	hdr.Data = uintptr(p)
-	hdr.Len = len(b)
+// RoundTripString returns both a string that refers to the data backing b, as
+// from AsString, and a slice that refers to the data backing that string, as
+// from OfString, covering both with a single mutation check instead of one for
+// each conversion.
+//
+// The same requirements apply as for AsString: the caller must ensure that the
+// contents of b are never again mutated, through either b or back.
+func RoundTripString(b []byte) (s string, back []byte) {
+	s = AsString(b)
+	return s, stringBytes(s)
+}
 
+// Freeze applies the same mutation checks as AsString to b, without converting
+// it to a string, and returns b.
+//
+// The caller must ensure that the contents of b are never again mutated.
+// Freeze is intended for data that must remain a []byte for API reasons but is
+// shared as if it were immutable.
+func Freeze(b []byte) []byte {
 	maybeDetectMutations(b)
-	return s
+	return b
 }
@@ -0,0 +1,926 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package unsafeslice
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"reflect"
+	"runtime"
+	"unsafe"
+)
+
+// This file contains generic counterparts to the reflection-based functions
+// in unsafeslice.go.
+
+// SliceOf is a constraint matching any slice type with elements of type T.
+type SliceOf[T any] interface {
+	~[]T
+}
+
+// DataOfSlice returns a pointer to the data backing s, even if s is non-nil
+// with a capacity of zero.
+//
+// DataOfSlice returns the data word of the slice header exactly, which is
+// the pointer that ConvertTo and the other conversions in this package carry
+// over to their results. It is nil only if s is nil or was constructed from a
+// nil pointer.
+func DataOfSlice[T any](s []T) unsafe.Pointer {
+	return unsafe.Pointer((*reflect.SliceHeader)(unsafe.Pointer(&s)).Data)
+}
+
+// Header returns the three words of the header of s: the address of its data,
+// its length, and its capacity.
+//
+// Header is intended for diagnostics and tests, such as asserting that a
+// conversion produced a slice that aliases the expected memory.
+func Header[T any](s []T) (data uintptr, length, capacity int) {
+	return uintptr(DataOfSlice(s)), len(s), cap(s)
+}
+
+// DescribeSlice returns a human-readable description of the header of s, such
+// as
+//
+//	[]uint32{data:0xc000012345, len:4, cap:6, elemSize:4, bytes:16/24, aligned:true}
+//
+// where bytes gives the length and capacity of s in bytes, and aligned reports
+// whether the data meets the alignment requirements of T.
+//
+// DescribeSlice is intended for diagnostics, such as bug reports about a
+// conversion that panicked.
+func DescribeSlice[T any](s []T) string {
+	data, length, capacity := Header(s)
+	size := unsafe.Sizeof(*new(T))
+	return fmt.Sprintf("%v{data:%#x, len:%d, cap:%d, elemSize:%d, bytes:%d/%d, aligned:%v}",
+		reflect.TypeOf(s), data, length, capacity, size,
+		uintptr(length)*size, uintptr(capacity)*size,
+		data%unsafe.Alignof(*new(T)) == 0)
+}
+
+// Overlap reports whether the memory spanned by the capacity of a intersects
+// that spanned by the capacity of b. Slices that span no memory, such as nil
+// slices or slices of zero-size elements, never overlap.
+//
+// Overlap compares capacities rather than lengths because writes through
+// either slice, such as by append, may reach anywhere within its capacity.
+func Overlap[A, B any](a []A, b []B) bool {
+	aStart := uintptr(DataOfSlice(a))
+	aEnd := aStart + uintptr(cap(a))*unsafe.Sizeof(*new(A))
+	bStart := uintptr(DataOfSlice(b))
+	bEnd := bStart + uintptr(cap(b))*unsafe.Sizeof(*new(B))
+	return aStart < aEnd && bStart < bEnd && aStart < bEnd && bStart < aEnd
+}
+
+// Grid returns a slice of rows slices, each of which refers to a consecutive
+// window of cols elements of backing. Writes through any row are visible in
+// backing, and vice versa.
+//
+// Grid panics unless rows and cols are non-negative and rows*cols equals
+// len(backing). Each row has capacity cols, so appending to a row never
+// overwrites the next one.
+func Grid[T any](backing []T, rows, cols int) [][]T {
+	if rows < 0 || cols < 0 || (cols != 0 && rows > len(backing)/cols) || rows*cols != len(backing) {
+		panic(fmt.Sprintf("Grid with %d rows of %d columns for backing slice of length %d", rows, cols, len(backing)))
+	}
+
+	grid := make([][]T, rows)
+	for i := range grid {
+		grid[i] = backing[i*cols : (i+1)*cols : (i+1)*cols]
+	}
+	return grid
+}
+
+// Aligned reports whether p meets the alignment requirements for a value of
+// type T.
+func Aligned[T any](p unsafe.Pointer) bool {
+	return uintptr(p)%unsafe.Alignof(*new(T)) == 0
+}
+
+// SliceAt returns a slice of length and capacity n located at p.
+//
+// The caller must ensure that p meets the alignment requirements for T, and
+// that the allocation to which p points contains at least n contiguous
+// elements. SliceAt panics if n is negative, and returns nil if n is zero.
+//
+// SliceAt is the preferred form of SetAt.
+func SliceAt[T any](p unsafe.Pointer, n int) []T {
+	if n < 0 {
+		panic(fmt.Sprintf("SliceAt with negative length %d", n))
+	}
+	if n == 0 {
+		return nil
+	}
+	checkAddrRange("SliceAt", p, n, unsafe.Sizeof(*new(T)))
+	return unsafe.Slice((*T)(p), n)
+}
+
+// SliceAtCap returns a slice of length n and capacity m located at p, so that
+// the slice may later be extended up to m elements. SliceAtCap panics unless
+// 0 <= n <= m, and returns nil if m is zero.
+//
+// The caller must ensure that p meets the alignment requirements for T, and
+// that the allocation to which p points contains at least m contiguous
+// elements.
+func SliceAtCap[T any](p unsafe.Pointer, n, m int) []T {
+	checkLenCap("SliceAtCap", n, m)
+	if m == 0 {
+		return nil
+	}
+	checkAddrRange("SliceAtCap", p, m, unsafe.Sizeof(*new(T)))
+	return unsafe.Slice((*T)(p), m)[:n]
+}
+
+// SetSliceAt sets *dst to a slice of length and capacity n located at p.
+//
+// SetSliceAt is the generic counterpart to SetAt: it has the same requirements,
+// but does not use reflection. It panics if n is negative.
+func SetSliceAt[T any](dst *[]T, p unsafe.Pointer, n int) {
+	// Check both n and the address range in one branch, without calling
+	// fmt.Sprintf, so that SetSliceAt is cheap enough to inline.
+	if n < 0 || !inAddrRange(p, n, unsafe.Sizeof(*new(T))) {
+		panic(&addrRangeError{"SetSliceAt", uintptr(p), n, unsafe.Sizeof(*new(T))})
+	}
+	*dst = unsafe.Slice((*T)(p), n)
+}
+
+// SetAtForeign is like SetSliceAt, but for memory that is not managed by the Go
+// runtime, such as memory allocated by C.malloc or mapped by syscall.Mmap.
+//
+// The garbage collector does not scan such memory, so any Go pointers stored
+// in it would not keep their referents alive. SetAtForeign therefore panics if
+// T contains pointers, as reported by ContainsPointers. As of Go 1.21,
+// SetAtPinned allows such types if the caller pins each stored pointer.
+func SetAtForeign[T any](dst *[]T, p unsafe.Pointer, n int) {
+	if ContainsPointers[T]() {
+		panic(fmt.Sprintf("SetAtForeign with element type %v, which contains pointers", reflect.TypeOf((*T)(nil)).Elem()))
+	}
+	if n < 0 {
+		panic(fmt.Sprintf("SetAtForeign with negative length %d", n))
+	}
+	checkAddrRange("SetAtForeign", p, n, unsafe.Sizeof(*new(T)))
+	*dst = unsafe.Slice((*T)(p), n)
+}
+
+// CloneToGo returns a copy of s in newly-allocated, Go-managed memory, so that
+// the copy remains valid after the memory backing s is freed or reused.
+//
+// CloneToGo also ends the mutation checks, such as those started by
+// ConvertToReadOnly or Freeze, that cover the memory of s: it repeats each
+// pending check immediately, panicking (or calling OnMutation) if it fails, and
+// ensures that no check started before CloneToGo reports a later mutation of
+// that memory, even one that has been evicted from the pending checks.
+//
+// Under the race detector, each check may also have started a goroutine to read
+// the memory, and that read cannot be ordered before CloneToGo returns, so a
+// later write may still be reported as a data race. Call
+// SetEagerRaceCheck(false) before starting checks on memory that will be
+// mutated after CloneToGo.
+func CloneToGo[T any](s []T) []T {
+	releaseMutationChecks(DataOfSlice(s), uintptr(cap(s))*unsafe.Sizeof(*new(T)))
+	if s == nil {
+		return nil
+	}
+	return append(make([]T, 0, len(s)), s...)
+}
+
+// MmapRecords returns a slice of T that refers to the memory of b, which is
+// typically the result of syscall.Mmap or a similar call that maps a file of
+// fixed-size records.
+//
+// MmapRecords returns an *AlignmentError if b is not suitably aligned for T,
+// and a *ConversionError if len(b) is not a multiple of the size of T, as when
+// the mapped file ends with a partial record. The capacity of the result is
+// its length, regardless of the capacity of b.
+//
+// Like SetAtForeign, MmapRecords panics if T contains pointers.
+func MmapRecords[T any](b []byte) ([]T, error) {
+	if ContainsPointers[T]() {
+		panic(fmt.Sprintf("MmapRecords with element type %v, which contains pointers", reflect.TypeOf((*T)(nil)).Elem()))
+	}
+
+	recs, err := convertTo[T, byte]("MmapRecords", b[:len(b):len(b)])
+	if cerr, ok := err.(*ConversionError); ok {
+		// The capacity passed to convertTo is the length of b.
+		cerr.Field = "length"
+	}
+	return recs, err
+}
+
+// ConvertTo returns a slice that refers to the same memory region as the slice
+// src, but at an arbitrary element type.
+//
+// The caller must ensure that src meets the alignment requirements for
+// DstElem, and that the length and capacity of src are integer multiples of
+// the size of DstElem. ConvertTo panics if either requirement is not met,
+// except that a result with capacity zero need not be aligned, since no memory
+// is ever accessed through it.
+//
+// The result has the same capacity as src, in bytes; to convert a subslice
+// whose capacity need not be a multiple of that size, use ReinterpretVisible.
+// As with ConvertAt, converting to []rune does not decode UTF-8, and a result
+// converted from the output of OfString remains read-only.
+//
+// ConvertTo is the generic counterpart to ConvertAt.
+func ConvertTo[DstElem, SrcElem any, Src SliceOf[SrcElem]](src Src) []DstElem {
+	dst, err := convertTo[DstElem, SrcElem]("ConvertTo", src)
+	if err != nil {
+		panic(err.Error())
+	}
+	return dst
+}
+
+// ConvertToReadOnly is like ConvertTo, but additionally applies the same
+// mutation checks as OfString to the resulting slice.
+//
+// The caller must ensure that the memory shared by src and the result is never
+// again mutated, through either slice or any other alias.
+func ConvertToReadOnly[DstElem, SrcElem any, Src SliceOf[SrcElem]](src Src) []DstElem {
+	dst, err := convertTo[DstElem, SrcElem]("ConvertToReadOnly", src)
+	if err != nil {
+		panic(err.Error())
+	}
+	maybeDetectMutations(ConvertTo[byte](dst))
+	return dst
+}
+
+// Reinterpret is like ConvertTo, but with only the destination and source
+// element types as type parameters, so that the common form
+// Reinterpret[Dst](src) can be written without naming the slice type.
+func Reinterpret[Dst, Src any](src []Src) []Dst {
+	dst, err := convertTo[Dst, Src]("Reinterpret", src)
+	if err != nil {
+		panic(err.Error())
+	}
+	return dst
+}
+
+// ReinterpretConsuming is like Reinterpret, but takes a pointer to the source
+// slice and sets it to nil after the conversion, so that the result is the
+// only remaining reference to the data through that variable.
+//
+// If the conversion panics, *src is left unmodified.
+func ReinterpretConsuming[Dst, Src any](src *[]Src) []Dst {
+	dst, err := convertTo[Dst, Src]("ReinterpretConsuming", *src)
+	if err != nil {
+		panic(err.Error())
+	}
+	*src = nil
+	return dst
+}
+
+// ConvertToCap is like Reinterpret, but extends the capacity of the result by
+// extraCap elements of Dst beyond the capacity of src, so that appends to the
+// result can use memory that follows src without reallocating. It is intended
+// for growable typed views over arenas and similar buffers whose allocation is
+// known to extend past the capacity of a particular slice.
+//
+// The caller must ensure that the allocation backing src extends at least
+// extraCap * unsafe.Sizeof(Dst) bytes beyond cap(src). ConvertToCap panics if
+// extraCap is negative, if src is nil and extraCap is positive, or under the
+// same conditions as Reinterpret, applying the alignment requirement whenever
+// the result has nonzero capacity, even if src itself has none.
+func ConvertToCap[Dst, Src any](src []Src, extraCap int) []Dst {
+	if extraCap < 0 {
+		panic(fmt.Sprintf("ConvertToCap with negative extraCap %d", extraCap))
+	}
+	dst, err := convertTo[Dst, Src]("ConvertToCap", src)
+	if err != nil {
+		panic(err.Error())
+	}
+	if extraCap == 0 {
+		return dst
+	}
+
+	m := cap(dst) + extraCap
+	if m < 0 {
+		panic(fmt.Sprintf("ConvertToCap: capacity %d + %d overflows int", cap(dst), extraCap))
+	}
+	p := DataOfSlice(src)
+	if p == nil {
+		panic("ConvertToCap with nil src and positive extraCap")
+	}
+	// convertTo does not check the alignment of a result with capacity zero,
+	// but this one will have capacity m.
+	if uintptr(p)%unsafe.Alignof(*new(Dst)) != 0 {
+		panic(newAlignmentError[Dst]("ConvertToCap", p).Error())
+	}
+	checkAddrRange("ConvertToCap", p, m, unsafe.Sizeof(*new(Dst)))
+	return unsafe.Slice((*Dst)(p), m)[:len(dst)]
+}
+
+// ReinterpretVisible is like Reinterpret, but considers only the elements of
+// src within its length: it requires only the length of src in bytes to be a
+// multiple of the size of Dst, and returns a slice whose capacity equals its
+// length. Reinterpret, in contrast, preserves the capacity of src, and so
+// panics for a subslice such as b[:4:6] even though the bytes beyond its length
+// are never used.
+//
+// Because the result has no spare capacity, appending to it always copies,
+// and never writes to the memory of src beyond its length.
+//
+// ReinterpretVisible is the generic counterpart to ConvertAtLen.
+func ReinterpretVisible[Dst, Src any](src []Src) []Dst {
+	dst, err := convertTo[Dst, Src]("ReinterpretVisible", src[:len(src):len(src)])
+	if cerr, ok := err.(*ConversionError); ok {
+		// The capacity passed to convertTo is the length of src.
+		cerr.Field = "length"
+	}
+	if err != nil {
+		panic(err.Error())
+	}
+	return dst
+}
+
+// ReinterpretSameSize is like Reinterpret, but panics unless Dst and Src have
+// the same size, so that each element of src corresponds to exactly one
+// element of the result. It guards against layout drift between two types
+// that are meant to be bit-compatible. Like Reinterpret, it also panics if src
+// is not suitably aligned for Dst.
+func ReinterpretSameSize[Dst, Src any](src []Src) []Dst {
+	dstSize, srcSize := unsafe.Sizeof(*new(Dst)), unsafe.Sizeof(*new(Src))
+	if dstSize != srcSize {
+		panic(fmt.Sprintf("ReinterpretSameSize: %v (%d bytes) and %v (%d bytes) differ in size", reflect.TypeOf((*Dst)(nil)).Elem(), dstSize, reflect.TypeOf((*Src)(nil)).Elem(), srcSize))
+	}
+
+	dst, err := convertTo[Dst, Src]("ReinterpretSameSize", src)
+	if err != nil {
+		panic(err.Error())
+	}
+	return dst
+}
+
+// ReinterpretPtrSlice is like Reinterpret, but allows Dst and Src to contain
+// pointers provided that their memory layouts are identical: they must have
+// the same size, and pointers at exactly the same offsets. ReinterpretPtrSlice
+// panics otherwise.
+//
+// ReinterpretPtrSlice is intended for types such as *A and *B, or structs with
+// matching pointer fields, for which the garbage collector's view of memory is
+// unchanged by the conversion. The caller remains responsible for the meaning
+// of the reinterpreted pointers.
+func ReinterpretPtrSlice[Dst, Src any](src []Src) []Dst {
+	dt := reflect.TypeOf((*Dst)(nil)).Elem()
+	st := reflect.TypeOf((*Src)(nil)).Elem()
+	if dt.Size() != st.Size() || !reflect.DeepEqual(appendPointerOffsets(nil, dt, 0), appendPointerOffsets(nil, st, 0)) {
+		panic(fmt.Sprintf("ReinterpretPtrSlice from %v to %v, which have different pointer layouts", st, dt))
+	}
+
+	dst, err := convertTo[Dst, Src]("ReinterpretPtrSlice", src)
+	if err != nil {
+		panic(err.Error())
+	}
+	return dst
+}
+
+// ReinterpretPrefix is like Reinterpret, but instead of panicking if the
+// length or capacity of src in bytes is not a multiple of the size of Dst, it
+// returns a slice covering the longest prefix of src that is. Any trailing
+// bytes are ignored. If Dst has size zero, or if src is not suitably aligned
+// for Dst and so has no usable prefix, ReinterpretPrefix returns nil: it never
+// panics.
+//
+// ReinterpretPrefix is intended for inputs of arbitrary length and alignment,
+// such as those produced by a fuzzer.
+func ReinterpretPrefix[Dst, Src any](src []Src) []Dst {
+	srcElemSize := unsafe.Sizeof(*new(Src))
+	dstElemSize := unsafe.Sizeof(*new(Dst))
+	if dstElemSize == 0 {
+		return nil
+	}
+
+	data := DataOfSlice(src)
+	dstCap := int(uintptr(cap(src)) * srcElemSize / dstElemSize)
+	dstLen := int(uintptr(len(src)) * srcElemSize / dstElemSize)
+	if dstCap != 0 && uintptr(data)%unsafe.Alignof(*new(Dst)) != 0 {
+		return nil
+	}
+	return unsafe.Slice((*Dst)(data), dstCap)[:dstLen]
+}
+
+// ContainsPointers reports whether the memory layout of T includes any words
+// that the garbage collector treats as pointers, such as pointers, strings,
+// slices, maps, channels, functions, or interfaces.
+//
+// ContainsPointers uses the same rules as the conversion functions in this
+// package, such as ConvertAt and ReinterpretNoPointers, that refuse to
+// reinterpret memory as pointers.
+func ContainsPointers[T any]() bool {
+	return containsPointers(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// ReinterpretNoPointers is like Reinterpret, but panics if either Dst or Src
+// contains Go pointers. Reinterpreting memory that holds pointers as some
+// other type, or vice versa, would cause the garbage collector to misinterpret
+// it and corrupt the heap.
+func ReinterpretNoPointers[Dst, Src any](src []Src) []Dst {
+	for _, t := range [...]reflect.Type{
+		reflect.TypeOf((*Dst)(nil)).Elem(),
+		reflect.TypeOf((*Src)(nil)).Elem(),
+	} {
+		if containsPointers(t) {
+			panic(fmt.Sprintf("ReinterpretNoPointers with element type %v, which contains pointers", t))
+		}
+	}
+
+	dst, err := convertTo[Dst, Src]("ReinterpretNoPointers", src)
+	if err != nil {
+		panic(err.Error())
+	}
+	return dst
+}
+
+// Bitcast returns a slice that refers to the same memory as src, with each
+// element reinterpreted bit-for-bit as a Dst, as when viewing a []float64 as a
+// []uint64 to manipulate its bits.
+//
+// Bitcast is narrower than Reinterpret: Dst and Src must both be numeric
+// types (integers, floating-point, or complex numbers, or types with one of
+// those as their underlying type) of the same size. It panics otherwise, or if
+// src is not suitably aligned for Dst.
+func Bitcast[Dst, Src any](src []Src) []Dst {
+	dt := reflect.TypeOf((*Dst)(nil)).Elem()
+	st := reflect.TypeOf((*Src)(nil)).Elem()
+	for _, t := range [...]reflect.Type{dt, st} {
+		if !isNumeric(t) {
+			panic(fmt.Sprintf("Bitcast with element type %v; need a numeric type", t))
+		}
+	}
+	if dt.Size() != st.Size() {
+		panic(fmt.Sprintf("Bitcast: %v (%d bytes) and %v (%d bytes) differ in size", dt, dt.Size(), st, st.Size()))
+	}
+
+	dst, err := convertTo[Dst, Src]("Bitcast", src)
+	if err != nil {
+		panic(err.Error())
+	}
+	return dst
+}
+
+// isNumeric reports whether t is an integer, floating-point, or complex type.
+func isNumeric(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+// ConvertToBE is like Reinterpret[Dst](src), but treats src as a sequence of
+// big-endian values: if the host is little-endian, it reverses the bytes of
+// each element of the result in place.
+//
+// ConvertToBE therefore overwrites src, and the caller loses its original
+// bytes. src must be writable: in particular, it must not refer to the data of
+// a string, as the result of OfString does. If mutation checks are enabled,
+// ConvertToBE panics if src is covered by a pending mutation check (see
+// CheckNow) or, in paranoid mode, by any check.
+//
+// Dst must be a fixed-width integer type, such as uint32 or int64.
+func ConvertToBE[Dst any](src []byte) []Dst {
+	return convertToEndian[Dst]("ConvertToBE", src, littleEndian)
+}
+
+// ConvertToLE is like Reinterpret[Dst](src), but treats src as a sequence of
+// little-endian values: if the host is big-endian, it reverses the bytes of
+// each element of the result in place.
+//
+// As with ConvertToBE, src is overwritten and must be writable.
+//
+// Dst must be a fixed-width integer type, such as uint32 or int64.
+func ConvertToLE[Dst any](src []byte) []Dst {
+	return convertToEndian[Dst]("ConvertToLE", src, !littleEndian)
+}
+
+// convertToEndian implements ConvertToBE and ConvertToLE, reversing the bytes
+// of each element of the result if swap is true.
+func convertToEndian[Dst any](op string, src []byte, swap bool) []Dst {
+	switch t := reflect.TypeOf((*Dst)(nil)).Elem(); t.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		panic(fmt.Sprintf("%s with element type %v; need a fixed-width integer type", op, t))
+	}
+	// Check even if swap is false, so that the mistake does not go unnoticed on
+	// hosts of one endianness.
+	checkWritable(op, DataOfSlice(src), uintptr(len(src)))
+
+	dst, err := convertTo[Dst, byte](op, src)
+	if err != nil {
+		panic(err.Error())
+	}
+	if swap {
+		reverseElemBytes(dst)
+	}
+	return dst
+}
+
+// reverseElemBytes reverses the bytes of each element of s, which must have a
+// size of 1, 2, 4, or 8 bytes.
+func reverseElemBytes[T any](s []T) {
+	switch unsafe.Sizeof(*new(T)) {
+	case 1:
+	case 2:
+		u := ReinterpretSameSize[uint16](s)
+		for i := range u {
+			u[i] = bits.ReverseBytes16(u[i])
+		}
+	case 4:
+		u := ReinterpretSameSize[uint32](s)
+		for i := range u {
+			u[i] = bits.ReverseBytes32(u[i])
+		}
+	case 8:
+		u := ReinterpretSameSize[uint64](s)
+		for i := range u {
+			u[i] = bits.ReverseBytes64(u[i])
+		}
+	default:
+		panic("unreachable")
+	}
+}
+
+// ConvertToAll splits b into consecutive columns of T, where column i has
+// lens[i] elements, and returns a slice referring to each column. The length
+// and capacity of each column are equal, so appending to one column never
+// overwrites the next.
+//
+// ConvertToAll validates the alignment of b and the total size of the columns
+// once, rather than once per column as repeated calls to ConvertTo would. It
+// panics if T has size zero, if b is not suitably aligned for T, if any length
+// is negative, or if the columns do not exactly cover len(b) bytes. As with
+// ConvertTo, columns that are all empty need not be aligned.
+//
+// To split b into columns of different element types, use ConvertAtAll.
+func ConvertToAll[T any](b []byte, lens []int) [][]T {
+	elemSize := unsafe.Sizeof(*new(T))
+	if elemSize == 0 {
+		panic(fmt.Sprintf("ConvertToAll with zero-size element type %v", reflect.TypeOf((*T)(nil)).Elem()))
+	}
+
+	total := 0
+	for _, n := range lens {
+		if n < 0 || n > len(b)/int(elemSize)-total {
+			panic(fmt.Sprintf("ConvertToAll: columns of lengths %v do not fit in %d bytes of %d-byte elements", lens, len(b), elemSize))
+		}
+		total += n
+	}
+	if uintptr(total)*elemSize != uintptr(len(b)) {
+		panic(fmt.Sprintf("ConvertToAll: columns of lengths %v cover %d of %d bytes", lens, uintptr(total)*elemSize, len(b)))
+	}
+	if data := DataOfSlice(b); total != 0 && uintptr(data)%unsafe.Alignof(*new(T)) != 0 {
+		panic(newAlignmentError[T]("ConvertToAll", data).Error())
+	}
+
+	all := unsafe.Slice((*T)(DataOfSlice(b)), total)
+	cols := make([][]T, len(lens))
+	off := 0
+	for i, n := range lens {
+		cols[i] = all[off : off+n : off+n]
+		off += n
+	}
+	return cols
+}
+
+// ConvertAtAll is like ConvertToAll, but for columns of different element
+// types. It splits b into consecutive columns, setting each dsts[i], which
+// must be of type *[]T for some T that does not contain pointers, to a slice
+// that refers to the next lens[i] elements of b. The length and capacity of
+// each column are equal, and an empty column is set to nil.
+//
+// ConvertAtAll validates every column before setting any dst. It panics if
+// the number of lengths and dsts differ, if any dst is not of the required
+// type, if any element type has size zero or contains pointers, if any length
+// is negative, if any non-empty column is not suitably aligned for its element
+// type, or if the columns do not exactly cover len(b) bytes.
+func ConvertAtAll(b []byte, lens []int, dsts ...interface{}) {
+	if len(lens) != len(dsts) {
+		panic(fmt.Sprintf("ConvertAtAll with %d lengths for %d dsts", len(lens), len(dsts)))
+	}
+
+	data := DataOfSlice(b)
+	off := uintptr(0)
+	for i, dst := range dsts {
+		dt := reflect.TypeOf(dst)
+		if dt == nil || dt.Kind() != reflect.Ptr || dt.Elem().Kind() != reflect.Slice {
+			panic(fmt.Sprintf("ConvertAtAll with dst type %T; need *[]T", dst))
+		}
+		elem := dt.Elem().Elem()
+		if containsPointers(elem) {
+			panic(fmt.Sprintf("ConvertAtAll with element type %v, which contains pointers", elem))
+		}
+		size := elem.Size()
+		if size == 0 {
+			panic(fmt.Sprintf("ConvertAtAll with zero-size element type %v", elem))
+		}
+		n := lens[i]
+		if n < 0 || uintptr(n) > (uintptr(len(b))-off)/size {
+			panic(fmt.Sprintf("ConvertAtAll: columns of lengths %v do not fit in %d bytes", lens, len(b)))
+		}
+		if addr, align := uintptr(data)+off, uintptr(elem.Align()); n != 0 && addr%align != 0 {
+			panic((&AlignmentError{Op: "ConvertAtAll", Addr: addr, Dst: dt.Elem(), Align: align}).Error())
+		}
+		off += uintptr(n) * size
+	}
+	if off != uintptr(len(b)) {
+		panic(fmt.Sprintf("ConvertAtAll: columns of lengths %v cover %d of %d bytes", lens, off, len(b)))
+	}
+
+	off = 0
+	for i, dst := range dsts {
+		setAt("ConvertAtAll", dst, unsafe.Add(data, off), lens[i], lens[i])
+		off += uintptr(lens[i]) * reflect.TypeOf(dst).Elem().Elem().Size()
+	}
+}
+
+// TryConvertTo is like ConvertTo, but returns an error instead of panicking if
+// src cannot be represented as a slice of DstElem: a *ConversionError if the
+// length or capacity of src does not fit, or an *AlignmentError if src is not
+// suitably aligned.
+func TryConvertTo[DstElem, SrcElem any, Src SliceOf[SrcElem]](src Src) ([]DstElem, error) {
+	return convertTo[DstElem, SrcElem]("TryConvertTo", src)
+}
+
+// CanConvertTo reports whether ConvertTo[DstElem](src) would succeed: that is,
+// whether src is suitably aligned for DstElem and the length and capacity of
+// src in bytes are integer multiples of the size of DstElem that fit in an
+// int.
+func CanConvertTo[DstElem, SrcElem any, Src SliceOf[SrcElem]](src Src) bool {
+	_, _, ok := ConvertedLen[DstElem]([]SrcElem(src))
+	return ok
+}
+
+// ConvertedLen returns the length and capacity of the slice that
+// ConvertTo[Dst](src) would return, without performing the conversion.
+// If the conversion would fail, ConvertedLen returns ok == false.
+func ConvertedLen[Dst, Src any](src []Src) (length, capacity int, ok bool) {
+	srcElemSize := unsafe.Sizeof(*new(Src))
+	dstElemSize := unsafe.Sizeof(*new(Dst))
+	capacity, capOK := elemCount(uintptr(cap(src))*srcElemSize, dstElemSize)
+	length, lenOK := elemCount(uintptr(len(src))*srcElemSize, dstElemSize)
+	if !capOK || !lenOK {
+		return 0, 0, false
+	}
+	if capacity != 0 && uintptr(DataOfSlice(src))%unsafe.Alignof(*new(Dst)) != 0 {
+		return 0, 0, false
+	}
+	return length, capacity, true
+}
+
+// ReinterpretLen returns the number of Dst elements occupying the same memory
+// as n elements of Src: that is, n * unsafe.Sizeof(Src) / unsafe.Sizeof(Dst).
+// It returns ok == false if n is negative, if Dst has size zero, or if the
+// result is not a whole number or overflows int.
+//
+// ReinterpretLen performs the same computation as ConvertTo does for the
+// length and capacity of its result, so that callers can validate or size a
+// buffer before converting it.
+func ReinterpretLen[Dst, Src any](n int) (int, bool) {
+	srcElemSize := unsafe.Sizeof(*new(Src))
+	dstElemSize := unsafe.Sizeof(*new(Dst))
+	if n < 0 || dstElemSize == 0 {
+		return 0, false
+	}
+	hi, nBytes := bits.Mul(uint(n), uint(srcElemSize))
+	if hi != 0 {
+		return 0, false
+	}
+	return elemCount(uintptr(nBytes), dstElemSize)
+}
+
+// convertTo implements TryConvertTo and the other conversions that report
+// errors, using op as the name of the operation in errors.
+func convertTo[DstElem, SrcElem any, Src SliceOf[SrcElem]](op string, src Src) ([]DstElem, error) {
+	capBytes := uintptr(cap(src)) * unsafe.Sizeof(*new(SrcElem))
+	lenBytes := uintptr(len(src)) * unsafe.Sizeof(*new(SrcElem))
+
+	// Take the data pointer from the slice header rather than &src[:1][0]:
+	// src may be non-nil with a capacity of zero, and the result should be too.
+	// A result with no capacity never accesses its data, so it need not be
+	// aligned.
+	data := DataOfSlice([]SrcElem(src))
+	if unsafe.Sizeof(*new(DstElem)) == 0 ||
+		capBytes%unsafe.Sizeof(*new(DstElem))+lenBytes%unsafe.Sizeof(*new(DstElem)) != 0 ||
+		capBytes/unsafe.Sizeof(*new(DstElem)) > math.MaxInt ||
+		capBytes != 0 && uintptr(data)%unsafe.Alignof(*new(DstElem)) != 0 {
+		return nil, convertToError[DstElem](op, capBytes, lenBytes, data)
+	}
+	return unsafe.Slice((*DstElem)(data), capBytes/unsafe.Sizeof(*new(DstElem)))[:lenBytes/unsafe.Sizeof(*new(DstElem))], nil
+}
+
+// convertToError returns the error for a conversion to a slice of DstElem of
+// capBytes bytes, of which lenBytes are in use, starting at data. It is never
+// inlined, to keep the construction of the error out of the caller.
+//
+//go:noinline
+func convertToError[DstElem any](op string, capBytes, lenBytes uintptr, data unsafe.Pointer) error {
+	dstElemSize := unsafe.Sizeof(*new(DstElem))
+	if dstElemSize == 0 || capBytes%dstElemSize != 0 || capBytes/dstElemSize > math.MaxInt {
+		// The length is no greater than the capacity, so it cannot overflow int
+		// unless the capacity does too.
+		return newConversionError[DstElem](op, "capacity", capBytes)
+	}
+	if lenBytes%dstElemSize != 0 {
+		return newConversionError[DstElem](op, "length", lenBytes)
+	}
+	return newAlignmentError[DstElem](op, data)
+}
+
+// newConversionError returns a *ConversionError for a conversion to a slice of
+// DstElem.
+func newConversionError[DstElem any](op, field string, srcBytes uintptr) error {
+	return &ConversionError{
+		Op:          op,
+		Field:       field,
+		SrcBytes:    srcBytes,
+		DstElem:     reflect.TypeOf((*DstElem)(nil)).Elem(),
+		DstElemSize: unsafe.Sizeof(*new(DstElem)),
+	}
+}
+
+// newAlignmentError returns an *AlignmentError for a conversion of the data at
+// p to a slice of DstElem.
+func newAlignmentError[DstElem any](op string, p unsafe.Pointer) error {
+	return &AlignmentError{
+		Op:    op,
+		Addr:  uintptr(p),
+		Dst:   reflect.TypeOf([]DstElem(nil)),
+		Align: unsafe.Alignof(*new(DstElem)),
+	}
+}
+
+// BytesOf returns a slice of length and capacity unsafe.Sizeof(*v) that refers
+// to the memory of the variable pointed to by v.
+//
+// The returned slice includes any padding bytes within *v, whose contents are
+// not specified. BytesOf is therefore suitable for hashing or comparing values
+// with identical in-memory layouts, but not for portable serialization.
+//
+// The caller must ensure that *v remains live for as long as the returned
+// slice is in use.
+func BytesOf[T any](v *T) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(v)), unsafe.Sizeof(*v))
+}
+
+// RawBytes returns a byte slice of length len(s) * unsafe.Sizeof(s[0]) and
+// capacity cap(s) * unsafe.Sizeof(s[0]) that refers to the memory of s, such
+// as to write all of s to a hash.Hash in a single call.
+//
+// RawBytes is equivalent to ConvertToReadOnly[byte](s), and the same mutation
+// checks apply: the caller must ensure that s is not mutated for as long as the
+// returned slice is in use. As with BytesOf, the result includes any padding
+// bytes within each element, so it is suitable for hashing values with
+// identical in-memory layouts, but not for portable serialization.
+func RawBytes[T any](s []T) []byte {
+	b, err := convertTo[byte, T]("RawBytes", s)
+	if err != nil {
+		panic(err.Error())
+	}
+	maybeDetectMutations(b)
+	return b
+}
+
+// ValueOf returns a pointer to a T that refers to the first unsafe.Sizeof(T)
+// bytes of b. It is the inverse of BytesOf: ValueOf[T](BytesOf(v)) == v.
+//
+// ValueOf panics if b is shorter than unsafe.Sizeof(T), if b is not suitably
+// aligned for T, or, as with PointerAt, if T contains pointers.
+func ValueOf[T any](b []byte) *T {
+	return pointerAt[T]("ValueOf", b, 0)
+}
+
+// SplitHeader returns a pointer to an H that refers to the first
+// unsafe.Sizeof(H) bytes of b, and a slice that refers to the remaining bytes.
+// It panics if b is too short to hold an H, if b is not aligned for H, or if H
+// contains pointers.
+//
+// The same caveats about byte order and padding apply as for BytesOf.
+func SplitHeader[H any](b []byte) (*H, []byte) {
+	h := pointerAt[H]("SplitHeader", b, 0)
+	return h, b[unsafe.Sizeof(*h):]
+}
+
+// PointerAt returns a pointer to a T that refers to the bytes of b starting at
+// offset off. It panics if off is negative, if fewer than unsafe.Sizeof(T)
+// bytes of b remain at off, or if that location is not aligned for T.
+//
+// PointerAt also panics if T contains pointers, as reported by ContainsPointers:
+// the garbage collector does not know to scan b for them. The returned pointer
+// may be used to both read and write the value in place.
+func PointerAt[T any](b []byte, off int) *T {
+	return pointerAt[T]("PointerAt", b, off)
+}
+
+// pointerAt returns a pointer to a T that refers to the bytes of b starting at
+// off, using op as the name of the operation in panics.
+func pointerAt[T any](op string, b []byte, off int) *T {
+	if ContainsPointers[T]() {
+		panic(fmt.Sprintf("%s with type %v, which contains pointers", op, reflect.TypeOf((*T)(nil)).Elem()))
+	}
+	size := unsafe.Sizeof(*new(T))
+	if off < 0 || off > len(b) || uintptr(len(b)-off) < size {
+		panic(fmt.Sprintf("%s: %d bytes at offset %d out of range for slice of length %d", op, size, off, len(b)))
+	}
+	p := unsafe.Add(DataOfSlice(b), off)
+	if align := unsafe.Alignof(*new(T)); uintptr(p)%align != 0 {
+		err := &AlignmentError{Op: op, Addr: uintptr(p), Dst: reflect.TypeOf((*T)(nil)), Align: align}
+		panic(err.Error())
+	}
+	return (*T)(p)
+}
+
+// AppendValue appends the bytes of the variable pointed to by v to buf and
+// returns the extended buffer, as if by append(buf, BytesOf(v)...).
+//
+// The same caveats about padding bytes apply as for BytesOf.
+func AppendValue[T any](buf []byte, v *T) []byte {
+	return append(buf, BytesOf(v)...)
+}
+
+// AsArray4 returns a pointer to an array that refers to the first 4 elements
+// of s. It panics if len(s) < 4.
+func AsArray4[T any](s []T) *[4]T { return (*[4]T)(s) }
+
+// AsArray8 returns a pointer to an array that refers to the first 8 elements
+// of s. It panics if len(s) < 8.
+func AsArray8[T any](s []T) *[8]T { return (*[8]T)(s) }
+
+// AsArray16 returns a pointer to an array that refers to the first 16
+// elements of s. It panics if len(s) < 16.
+func AsArray16[T any](s []T) *[16]T { return (*[16]T)(s) }
+
+// AsArray32 returns a pointer to an array that refers to the first 32
+// elements of s. It panics if len(s) < 32.
+func AsArray32[T any](s []T) *[32]T { return (*[32]T)(s) }
+
+// Zero sets every element of s to the zero value of T, such as to wipe key
+// material from memory aliased by s once it is no longer needed.
+//
+// Zero is never inlined, and keeps s alive until the stores are complete, so
+// the compiler cannot eliminate them even if s is not read afterward. It
+// clears only the memory of s itself: any copies of the data made elsewhere,
+// such as by append or by the runtime when growing a goroutine stack, are not
+// affected.
+//
+//go:noinline
+func Zero[T any](s []T) {
+	var zero T
+	for i := range s {
+		s[i] = zero
+	}
+	runtime.KeepAlive(s)
+}
+
+// MakeAligned returns a new zeroed byte slice of length n * unsafe.Sizeof(T)
+// whose data is aligned for T, so that it may later be converted to []T with
+// ConvertTo or Reinterpret.
+//
+// MakeAligned panics if T contains pointers: the garbage collector would not
+// scan pointers stored into the returned bytes.
+func MakeAligned[T any](n int) []byte {
+	if ContainsPointers[T]() {
+		panic(fmt.Sprintf("MakeAligned with element type %v, which contains pointers", reflect.TypeOf((*T)(nil)).Elem()))
+	}
+	return ConvertTo[byte](make([]T, n))
+}
+
+// SliceOfString returns a slice of T that refers to the data backing the
+// string s.
+//
+// SliceOfString panics if len(s) is not a multiple of the size of T, if the
+// data backing a non-empty s is not aligned for T, or if that data would extend
+// past the end of the address space (which can only happen for a string header
+// that was constructed incorrectly). It checks all of these before reading any
+// of the data.
+//
+// As with OfString, the caller must ensure that the contents of the slice are
+// never mutated, and the same mutation checks apply.
+func SliceOfString[T any](s string) []T {
+	p := DataOfString(s)
+	if len(s) != 0 && uintptr(p)%unsafe.Alignof(*new(T)) != 0 {
+		panic(newAlignmentError[T]("SliceOfString", p).Error())
+	}
+	if _, ok := elemCount(uintptr(len(s)), unsafe.Sizeof(*new(T))); !ok {
+		panic(newConversionError[T]("SliceOfString", "length", uintptr(len(s))).Error())
+	}
+	checkAddrRange("SliceOfString", p, len(s), 1)
+	return ConvertTo[T](OfString(s))
+}
+
+// StringOfSlice returns a string of length len(s) * unsafe.Sizeof(s[0]) that
+// refers to the data backing the slice s.
+//
+// As with AsString, the caller must ensure that the contents of the slice are
+// never again mutated, and that its memory either is managed by the Go garbage
+// collector or remains valid for the remainder of this process's lifetime.
+// The same mutation checks apply.
+func StringOfSlice[T any](s []T) string {
+	return AsString(ConvertTo[byte](s))
+}
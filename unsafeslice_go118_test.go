@@ -0,0 +1,1440 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package unsafeslice_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/bcmills/unsafeslice"
+)
+
+func TestAligned(t *testing.T) {
+	buf := make([]uint64, 2)
+	p := unsafe.Pointer(&buf[0])
+	if !unsafeslice.Aligned[uint64](p) {
+		t.Errorf("Aligned[uint64](%p) = false; want true", p)
+	}
+
+	q := unsafe.Add(p, 1)
+	if unsafeslice.Aligned[uint64](q) {
+		t.Errorf("Aligned[uint64](%p) = true; want false", q)
+	}
+	if !unsafeslice.Aligned[byte](q) {
+		t.Errorf("Aligned[byte](%p) = false; want true", q)
+	}
+}
+
+func ExampleSliceAt() {
+	original := []byte("Hello, world!")
+	p, n := asCPointer(original)
+
+	alias := unsafeslice.SliceAt[byte](unsafe.Pointer(p), n)
+
+	fmt.Printf("original: %s\n", original)
+	fmt.Printf("alias: %s\n", alias)
+	copy(alias, "Adios")
+	fmt.Printf("original: %s\n", original)
+	fmt.Printf("alias: %s\n", alias)
+
+	// Output:
+	// original: Hello, world!
+	// alias: Hello, world!
+	// original: Adios, world!
+	// alias: Adios, world!
+}
+
+func TestSliceAtEmpty(t *testing.T) {
+	var x uint32
+	if s := unsafeslice.SliceAt[uint32](unsafe.Pointer(&x), 0); s != nil {
+		t.Errorf("SliceAt(%p, 0) = %v; want nil", &x, s)
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("SliceAt with negative length failed to panic as expected.")
+		}
+	}()
+	unsafeslice.SliceAt[uint32](unsafe.Pointer(&x), -1)
+}
+
+func TestSliceAtAddressOverflow(t *testing.T) {
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+			if s := fmt.Sprint(msg); !strings.Contains(s, "SliceAt: ") || !strings.Contains(s, "overflow the address space") {
+				t.Errorf("SliceAt panicked with %q; want address-space overflow", s)
+			}
+		} else {
+			t.Errorf("SliceAt failed to panic as expected.")
+		}
+	}()
+
+	var x uint64
+	unsafeslice.SliceAt[uint64](unsafe.Pointer(&x), math.MaxInt)
+}
+
+func TestSetAtForeign(t *testing.T) {
+	// Stand in for foreign memory with a Go array: SetAtForeign cannot tell the
+	// difference, and the test cannot portably allocate C memory.
+	var buf [4]uint32
+	var s []uint32
+	unsafeslice.SetAtForeign(&s, unsafe.Pointer(&buf[0]), len(buf))
+	if len(s) != len(buf) || &s[0] != &buf[0] {
+		t.Errorf("SetAtForeign(_, %p, %d) set %v; want alias of buf", &buf[0], len(buf), s)
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("SetAtForeign with pointer element type failed to panic as expected.")
+		}
+	}()
+	var ps []*uint32
+	unsafeslice.SetAtForeign(&ps, unsafe.Pointer(&buf[0]), 1)
+}
+
+func TestCloneToGo(t *testing.T) {
+	s := []uint16{1, 2, 3}
+	c := unsafeslice.CloneToGo(s[:2])
+	if len(c) != 2 || cap(c) != 2 || c[0] != 1 || c[1] != 2 {
+		t.Errorf("CloneToGo(%v) = %v (cap %d); want [1 2] (cap 2)", s[:2], c, cap(c))
+	}
+	if unsafeslice.Overlap(c, s) {
+		t.Errorf("CloneToGo(%v) returned a slice that overlaps its input", s[:2])
+	}
+	if c := unsafeslice.CloneToGo([]uint16(nil)); c != nil {
+		t.Errorf("CloneToGo(nil) = %v; want nil", c)
+	}
+
+	// A clone of a read-only view ends its mutation checks, so the original may
+	// then be modified freely.
+	unsafeslice.SetEagerRaceCheck(false)
+	defer unsafeslice.SetEagerRaceCheck(true)
+	b := []byte("Hello, world!")
+	ro := unsafeslice.ConvertToReadOnly[byte](b)
+	clone := unsafeslice.CloneToGo(ro)
+	copy(b, "Kaboom")
+	unsafeslice.CheckNow()
+	if string(clone) != "Hello, world!" {
+		t.Errorf("CloneToGo(%q) = %q", "Hello, world!", clone)
+	}
+}
+
+func TestMmapRecords(t *testing.T) {
+	type record struct {
+		ID    uint32
+		Value float32
+	}
+
+	// Stand in for a mapped file with an aligned Go buffer.
+	buf := unsafeslice.MakeAligned[record](2)
+	recs, err := unsafeslice.MmapRecords[record](buf)
+	if err != nil {
+		t.Fatalf("MmapRecords[record](buf) = %v", err)
+	}
+	if len(recs) != 2 || cap(recs) != 2 || unsafe.Pointer(&recs[0]) != unsafe.Pointer(&buf[0]) {
+		t.Errorf("MmapRecords[record](buf) = %v (cap %d); want 2 records aliasing buf", recs, cap(recs))
+	}
+
+	_, err = unsafeslice.MmapRecords[record](buf[:12])
+	var cerr *unsafeslice.ConversionError
+	if !errors.As(err, &cerr) {
+		t.Errorf("MmapRecords[record](buf[:12]) = %v; want *ConversionError", err)
+	} else {
+		t.Logf("MmapRecords[record](buf[:12]): %v", err)
+		if cerr.Field != "length" || cerr.SrcBytes != 12 {
+			t.Errorf("MmapRecords[record](buf[:12]): Field = %q, SrcBytes = %d; want \"length\", 12", cerr.Field, cerr.SrcBytes)
+		}
+		if msg := err.Error(); !strings.Contains(msg, "src length (12 bytes)") {
+			t.Errorf("MmapRecords[record](buf[:12]) error %q does not describe the src length", msg)
+		}
+	}
+
+	_, err = unsafeslice.MmapRecords[record](buf[1:9])
+	var aerr *unsafeslice.AlignmentError
+	if !errors.As(err, &aerr) {
+		t.Errorf("MmapRecords[record](buf[1:9]) = %v; want *AlignmentError", err)
+	} else {
+		t.Logf("MmapRecords[record](buf[1:9]): %v", err)
+	}
+}
+
+func TestSliceAtCap(t *testing.T) {
+	buf := make([]uint32, 8)
+	s := unsafeslice.SliceAtCap[uint32](unsafe.Pointer(&buf[0]), 2, 8)
+	if len(s) != 2 || cap(s) != 8 || &s[0] != &buf[0] {
+		t.Errorf("SliceAtCap(%p, 2, 8) = %v (cap %d); want alias of %v (cap 8)", &buf[0], s, cap(s), buf[:2])
+	}
+	if s = append(s, 7); &s[0] != &buf[0] || buf[2] != 7 {
+		t.Errorf("append to SliceAtCap result reallocated instead of writing through.")
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("SliceAtCap with length greater than capacity failed to panic as expected.")
+		}
+	}()
+	unsafeslice.SliceAtCap[uint32](unsafe.Pointer(&buf[0]), 3, 2)
+}
+
+func TestSetSliceAt(t *testing.T) {
+	buf := []uint32{1, 2, 3}
+	var s []uint32
+	unsafeslice.SetSliceAt(&s, unsafe.Pointer(&buf[0]), 2)
+	if len(s) != 2 || cap(s) != 2 || &s[0] != &buf[0] {
+		t.Errorf("SetSliceAt(_, %p, 2) set %v (cap %d); want alias of %v", &buf[0], s, cap(s), buf[:2])
+	}
+
+	avg := testing.AllocsPerRun(1000, func() {
+		unsafeslice.SetSliceAt(&s, unsafe.Pointer(&buf[0]), len(buf))
+	})
+	if avg > 0 {
+		t.Errorf("SetSliceAt made %v allocations; want 0", avg)
+	}
+
+	defer func() {
+		msg := recover()
+		t.Logf("recovered: %v", msg)
+		if got, want := fmt.Sprint(msg), "SetSliceAt with negative length -1"; got != want {
+			t.Errorf("SetSliceAt(_, _, -1) panicked with %q; want %q", got, want)
+		}
+	}()
+	unsafeslice.SetSliceAt(&s, unsafe.Pointer(&buf[0]), -1)
+}
+
+func TestDataOf(t *testing.T) {
+	buf := make([]uint32, 4)
+	if p := unsafeslice.DataOfSlice(buf[2:2]); p != unsafe.Pointer(&buf[2]) {
+		t.Errorf("DataOfSlice(buf[2:2]) = %p; want %p", p, &buf[2])
+	}
+
+	if p := unsafeslice.DataOfSlice([]uint32{}); p == nil {
+		t.Errorf("DataOfSlice([]uint32{}) = nil; want non-nil")
+	}
+	if p := unsafeslice.DataOfSlice([]uint32(nil)); p != nil {
+		t.Errorf("DataOfSlice([]uint32(nil)) = %p; want nil", p)
+	}
+	if p := unsafeslice.DataOfSlice(unsafeslice.ConvertTo[byte](buf[:0:0])); p != unsafe.Pointer(&buf[0]) {
+		t.Errorf("DataOfSlice(ConvertTo[byte](buf[:0:0])) = %p; want %p", p, &buf[0])
+	}
+
+	s := "Hello, world!"
+	if p, want := unsafeslice.DataOfString(s[7:]), unsafe.Add(unsafeslice.DataOfString(s), 7); p != want {
+		t.Errorf("DataOfString(s[7:]) = %p; want %p", p, want)
+	}
+}
+
+func ExampleConvertTo() {
+	// For this example, we're going to do a transformation on some ASCII text.
+	// That transformation is not endian-sensitive, so we can reinterpret the text
+	// as a slice of uint32s to process it word-at-a-time instead of
+	// byte-at-a-time.
+
+	const input = "HELLO, WORLD!"
+
+	// Allocate an aligned backing buffer.
+	buf := make([]uint32, (len(input)+3)/4)
+
+	// Reinterpret it as a byte slice so that we can copy in our text.
+	alias := unsafeslice.ConvertTo[byte](buf)
+	copy(alias, input)
+
+	// Perform an endian-insensitive transformation word-by-word instead of
+	// byte-by-byte.
+	for i := range buf {
+		buf[i] |= 0x20202020
+	}
+
+	// Read the result back out of the byte-slice view to interpret it as text.
+	fmt.Printf("%s\n", alias[:len(input)])
+
+	// Output:
+	// hello, world!
+}
+
+func TestConvertToArrays(t *testing.T) {
+	buf := make([]byte, 64)
+	blocks := unsafeslice.ConvertTo[[16]byte](buf)
+	if len(blocks) != 4 || cap(blocks) != 4 {
+		t.Errorf("ConvertTo[[16]byte](make([]byte, 64)): len %d, cap %d; want 4, 4", len(blocks), cap(blocks))
+	}
+	blocks[3][15] = 1
+	if buf[63] != 1 {
+		t.Errorf("write to blocks[3][15] not visible in buf[63]")
+	}
+
+	if empty := unsafeslice.ConvertTo[[16]byte](buf[:0]); len(empty) != 0 || cap(empty) != 4 {
+		t.Errorf("ConvertTo[[16]byte](buf[:0]): len %d, cap %d; want 0, 4", len(empty), cap(empty))
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("ConvertTo[[16]byte] of 63 bytes failed to panic as expected.")
+		}
+	}()
+	unsafeslice.ConvertTo[[16]byte](make([]byte, 63))
+}
+
+// TestConvertToMisaligned verifies that ConvertTo rejects misaligned data
+// rather than returning a slice that faults on architectures, such as 32-bit
+// ARM, that require aligned access.
+func TestConvertToMisaligned(t *testing.T) {
+	buf := unsafeslice.MakeAligned[uint64](2)
+	misaligned := buf[4:12:12]
+	if unsafe.Alignof(uint64(0)) < 8 {
+		// On 386 and arm, uint64 has only 4-byte alignment.
+		misaligned = buf[1:9:9]
+	}
+
+	if unsafeslice.CanConvertTo[uint64](misaligned) {
+		t.Errorf("CanConvertTo[uint64](misaligned) = true; want false")
+	}
+
+	_, err := unsafeslice.TryConvertTo[uint64](misaligned)
+	var aerr *unsafeslice.AlignmentError
+	if !errors.As(err, &aerr) {
+		t.Fatalf("TryConvertTo[uint64](misaligned) = %v; want *AlignmentError", err)
+	}
+	t.Logf("TryConvertTo[uint64](misaligned): %v", err)
+	if aerr.Align != unsafe.Alignof(uint64(0)) {
+		t.Errorf("TryConvertTo[uint64](misaligned): Align = %d; want %d", aerr.Align, unsafe.Alignof(uint64(0)))
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+			if want := strings.Replace(err.Error(), "TryConvertTo", "ConvertTo", 1); msg != want {
+				t.Errorf("ConvertTo[uint64](misaligned) panicked with %#v; want %#v", msg, want)
+			}
+		} else {
+			t.Errorf("ConvertTo[uint64](misaligned) failed to panic as expected.")
+		}
+	}()
+	unsafeslice.ConvertTo[uint64](misaligned)
+}
+
+// TestInlinable verifies that calls to SetSliceAt are inlined,
+// so that they cost no more than constructing the slice directly.
+func TestInlinable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiler invocation in short mode")
+	}
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skipf("go command not found: %v", err)
+	}
+	modDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module inlinable\n\ngo 1.18\n\nrequire github.com/bcmills/unsafeslice v0.0.0\n\nreplace github.com/bcmills/unsafeslice => " + modDir + "\n",
+		"main.go": `package main
+
+import (
+	"unsafe"
+
+	"github.com/bcmills/unsafeslice"
+)
+
+var b = make([]byte, 16)
+
+func main() {
+	var u []uint32
+	unsafeslice.SetSliceAt(&u, unsafe.Pointer(&b[0]), 4)
+	println(len(u))
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(goCmd, "build", "-gcflags=-m", "-o", os.DevNull, ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v: %v\n%s", cmd, err, out)
+	}
+	if !bytes.Contains(out, []byte("inlining call to unsafeslice.SetSliceAt[")) {
+		t.Errorf("SetSliceAt was not inlined:\n%s", out)
+	}
+}
+
+func TestConvertToEmpty(t *testing.T) {
+	if u := unsafeslice.ConvertTo[uint32]([]byte{}); u == nil || len(u) != 0 || cap(u) != 0 {
+		t.Errorf("ConvertTo[uint32]([]byte{}) = %#v (cap %d); want non-nil empty slice", u, cap(u))
+	}
+	if u := unsafeslice.ConvertTo[uint32]([]byte(nil)); u != nil {
+		t.Errorf("ConvertTo[uint32]([]byte(nil)) = %#v; want nil", u)
+	}
+}
+
+// TestConvertToMisalignedEmpty verifies that results with capacity zero are
+// not rejected for misalignment, since no memory is accessed through them.
+func TestConvertToMisalignedEmpty(t *testing.T) {
+	buf := unsafeslice.MakeAligned[uint32](2)
+
+	// Slicing buf[1:1:1] would not advance the data pointer, since the result
+	// has no capacity, so construct the misaligned empty slice directly.
+	p := unsafe.Add(unsafe.Pointer(&buf[0]), 1)
+	empty := unsafe.Slice((*byte)(p), 0)
+	if unsafeslice.DataOfSlice(empty) != p {
+		t.Fatalf("DataOfSlice(empty) = %p; want %p", unsafeslice.DataOfSlice(empty), p)
+	}
+
+	if u := unsafeslice.ConvertTo[uint32](empty); len(u) != 0 || cap(u) != 0 {
+		t.Errorf("ConvertTo[uint32](empty) = %v (cap %d); want empty", u, cap(u))
+	}
+	if !unsafeslice.CanConvertTo[uint32](empty) {
+		t.Errorf("CanConvertTo[uint32](empty) = false; want true")
+	}
+	var u32 []uint32
+	if err := unsafeslice.TryConvertAt(&u32, empty); err != nil || len(u32) != 0 || cap(u32) != 0 {
+		t.Errorf("TryConvertAt(&u32, empty) = %v, setting u32 to %v (cap %d); want <nil> and empty", err, u32, cap(u32))
+	}
+	func() {
+		defer func() {
+			if msg := recover(); msg != nil {
+				t.Logf("recovered: %v", msg)
+			} else {
+				t.Errorf("ConvertToCap[uint32](empty, 1) failed to panic as expected.")
+			}
+		}()
+		unsafeslice.ConvertToCap[uint32](empty, 1)
+	}()
+	if u := unsafeslice.ReinterpretPrefix[uint32](buf[1:3:3]); len(u) != 0 || cap(u) != 0 {
+		t.Errorf("ReinterpretPrefix[uint32](buf[1:3:3]) = %v (cap %d); want empty", u, cap(u))
+	}
+
+	var s string
+	hdr := (*reflect.StringHeader)(unsafe.Pointer(&s))
+	hdr.Data = uintptr(p)
+	hdr.Len = 0
+	if u := unsafeslice.SliceOfString[uint32](s); len(u) != 0 {
+		t.Errorf("SliceOfString[uint32](s) = %v; want empty", u)
+	}
+}
+
+func TestConvertToReadOnly(t *testing.T) {
+	u32 := []uint32{0x00102030, 0x40506070}
+	b := unsafeslice.ConvertToReadOnly[byte](u32)
+
+	if len(b) != 8 || unsafe.Pointer(&b[0]) != unsafe.Pointer(&u32[0]) {
+		t.Errorf("ConvertToReadOnly[byte](%x) = %x; want an alias of length 8", u32, b)
+	}
+}
+
+func TestReinterpret(t *testing.T) {
+	b := make([]byte, 8, 12)
+	u32 := unsafeslice.Reinterpret[uint32](b)
+	if len(u32) != 2 || cap(u32) != 3 || unsafe.Pointer(&u32[0]) != unsafe.Pointer(&b[0]) {
+		t.Errorf("Reinterpret[uint32](b) = %v (cap %d); want an alias of length 2 and capacity 3", u32, cap(u32))
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("Reinterpret failed to panic as expected.")
+		}
+	}()
+	unsafeslice.Reinterpret[uint32](b[:6])
+}
+
+func TestConvertToCap(t *testing.T) {
+	arena := make([]uint32, 8)
+	b := unsafeslice.ConvertTo[byte](arena)[:4:8]
+
+	u32 := unsafeslice.ConvertToCap[uint32](b, 6)
+	if len(u32) != 1 || cap(u32) != 8 || &u32[0] != &arena[0] {
+		t.Errorf("ConvertToCap[uint32](b[:4:8], 6) = %v (cap %d); want an alias of length 1 and capacity 8", u32, cap(u32))
+	}
+	if u32 = append(u32, 7); &u32[0] != &arena[0] || arena[1] != 7 {
+		t.Errorf("append to ConvertToCap result reallocated instead of writing through.")
+	}
+
+	if u32 := unsafeslice.ConvertToCap[uint32](b, 0); cap(u32) != 2 {
+		t.Errorf("cap(ConvertToCap[uint32](b[:4:8], 0)) = %d; want 2", cap(u32))
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("ConvertToCap with negative extraCap failed to panic as expected.")
+		}
+	}()
+	unsafeslice.ConvertToCap[uint32](b, -1)
+}
+
+func TestReinterpretVisible(t *testing.T) {
+	b := unsafeslice.MakeAligned[uint32](2)[:4:6]
+	u32 := unsafeslice.ReinterpretVisible[uint32](b)
+	if len(u32) != 1 || cap(u32) != 1 || unsafe.Pointer(&u32[0]) != unsafe.Pointer(&b[0]) {
+		t.Errorf("ReinterpretVisible[uint32](b[:4:6]) = %v (cap %d); want an alias of length and capacity 1", u32, cap(u32))
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("ReinterpretVisible failed to panic as expected.")
+		}
+	}()
+	unsafeslice.ReinterpretVisible[uint32](b[:3])
+}
+
+func TestReinterpretSameSize(t *testing.T) {
+	type a struct{ x, y int32 }
+	type b struct{ z uint64 }
+
+	as := make([]a, 2, 3)
+	bs := unsafeslice.ReinterpretSameSize[b](as)
+	if len(bs) != 2 || cap(bs) != 3 || unsafe.Pointer(&bs[0]) != unsafe.Pointer(&as[0]) {
+		t.Errorf("ReinterpretSameSize[b](as) = %v (cap %d); want an alias of length 2 and capacity 3", bs, cap(bs))
+	}
+
+	buf := make([]uint64, 3)
+	misaligned := unsafe.Slice((*[8]byte)(unsafe.Add(unsafe.Pointer(&buf[0]), 1)), 2)
+	func() {
+		defer func() {
+			if msg := recover(); msg != nil {
+				t.Logf("recovered: %v", msg)
+			} else {
+				t.Errorf("ReinterpretSameSize with misaligned src failed to panic as expected.")
+			}
+		}()
+		unsafeslice.ReinterpretSameSize[uint64](misaligned)
+	}()
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("ReinterpretSameSize failed to panic as expected.")
+		}
+	}()
+	unsafeslice.ReinterpretSameSize[uint32](as)
+}
+
+func TestReinterpretNoPointers(t *testing.T) {
+	type point struct{ x, y int32 }
+	ps := unsafeslice.ReinterpretNoPointers[point](make([]uint64, 2))
+	if len(ps) != 2 {
+		t.Errorf("len(ReinterpretNoPointers[point](make([]uint64, 2))) = %v; want 2", len(ps))
+	}
+
+	type named struct {
+		id   uint64
+		name string
+	}
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("ReinterpretNoPointers[named] failed to panic as expected.")
+		}
+	}()
+	unsafeslice.ReinterpretNoPointers[named](make([]uint64, 6))
+}
+
+// mustPanic calls f and reports an error if it does not panic, returning the
+// recovered value otherwise. desc describes the call for the error message.
+func mustPanic(t *testing.T, desc string, f func()) (msg interface{}) {
+	t.Helper()
+	defer func() {
+		if msg = recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("%s failed to panic as expected.", desc)
+		}
+	}()
+	f()
+	return nil
+}
+
+func TestBitcast(t *testing.T) {
+	fs := []float64{1, -2, math.Inf(1)}
+	us := unsafeslice.Bitcast[uint64](fs)
+	for i, f := range fs {
+		if want := math.Float64bits(f); us[i] != want {
+			t.Errorf("Bitcast[uint64](fs)[%d] = %#x; want %#x", i, us[i], want)
+		}
+	}
+	us[1] &^= 1 << 63
+	if fs[1] != 2 {
+		t.Errorf("after clearing the sign bit of us[1], fs[1] = %v; want 2", fs[1])
+	}
+
+	type celsius float32
+	if cs := unsafeslice.Bitcast[celsius](make([]int32, 2, 4)); len(cs) != 2 || cap(cs) != 4 {
+		t.Errorf("Bitcast[celsius](make([]int32, 2, 4)) has length %d and capacity %d; want 2 and 4", len(cs), cap(cs))
+	}
+
+	for _, tc := range []struct {
+		desc string
+		f    func()
+	}{
+		{"different sizes", func() { unsafeslice.Bitcast[uint32](fs) }},
+		{"struct type", func() { unsafeslice.Bitcast[struct{ x, y int32 }](us) }},
+		{"pointer type", func() { unsafeslice.Bitcast[uintptr](make([]*byte, 1)) }},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			mustPanic(t, "Bitcast", tc.f)
+		})
+	}
+}
+
+func TestConvertToEndian(t *testing.T) {
+	be := unsafeslice.MakeAligned[uint32](2)
+	copy(be, "\x01\x02\x03\x04\x05\x06\x07\x08")
+	if got, want := unsafeslice.ConvertToBE[uint32](be), []uint32{0x01020304, 0x05060708}; fmt.Sprintf("%x", got) != fmt.Sprintf("%x", want) {
+		t.Errorf("ConvertToBE[uint32] = %x; want %x", got, want)
+	}
+
+	le := unsafeslice.MakeAligned[uint16](2)
+	copy(le, "\x01\x02\x03\x04")
+	if got, want := unsafeslice.ConvertToLE[uint16](le), []uint16{0x0201, 0x0403}; fmt.Sprintf("%x", got) != fmt.Sprintf("%x", want) {
+		t.Errorf("ConvertToLE[uint16] = %x; want %x", got, want)
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("ConvertToBE[float64] failed to panic as expected.")
+		}
+	}()
+	unsafeslice.ConvertToBE[float64](unsafeslice.MakeAligned[float64](1))
+}
+
+// TestConvertToEndianString verifies that ConvertToBE refuses to overwrite the
+// data of a string.
+func TestConvertToEndianString(t *testing.T) {
+	if !safetyChecksEnabled {
+		t.Skip("only checked builds can tell that data came from a string")
+	}
+	unsafeslice.SetEagerRaceCheck(false)
+	defer unsafeslice.SetEagerRaceCheck(true)
+
+	// Build s at run time, so that a failure to panic overwrites the heap rather
+	// than faulting on read-only data.
+	s := string([]byte("\x01\x02\x03\x04"))
+	b := unsafeslice.OfString(s)
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("ConvertToBE[uint16](OfString(%q)) failed to panic as expected.", s)
+		}
+	}()
+	unsafeslice.ConvertToBE[uint16](b)
+}
+
+func TestTryConvertTo(t *testing.T) {
+	cases := []struct {
+		desc  string
+		src   []byte
+		field string
+	}{
+		{
+			desc:  "incompatible capacity",
+			src:   []byte("foobar")[:4:6],
+			field: "capacity",
+		},
+		{
+			desc:  "incompatible length",
+			src:   []byte("foobar\x00\x00")[:6],
+			field: "length",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dst, err := unsafeslice.TryConvertTo[uint32](tc.src)
+			var cerr *unsafeslice.ConversionError
+			if !errors.As(err, &cerr) {
+				t.Fatalf("TryConvertTo[uint32](%q) = %v, %v; want *ConversionError", tc.src, dst, err)
+			}
+			t.Logf("TryConvertTo[uint32](%q): %v", tc.src, err)
+			if cerr.Field != tc.field {
+				t.Errorf("TryConvertTo[uint32](%q): Field = %q; want %q", tc.src, cerr.Field, tc.field)
+			}
+		})
+	}
+
+	t.Run("zero-size element", func(t *testing.T) {
+		src := []byte("foobar")
+		dst, err := unsafeslice.TryConvertTo[struct{}](src)
+		var cerr *unsafeslice.ConversionError
+		if !errors.As(err, &cerr) {
+			t.Fatalf("TryConvertTo[struct{}](%q) = %v, %v; want *ConversionError", src, dst, err)
+		}
+		t.Logf("TryConvertTo[struct{}](%q): %v", src, err)
+		if cerr.DstElemSize != 0 {
+			t.Errorf("TryConvertTo[struct{}](%q): DstElemSize = %d; want 0", src, cerr.DstElemSize)
+		}
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		src := []byte("foobar\x00\x00")[:4]
+		dst, err := unsafeslice.TryConvertTo[uint32](src)
+		if err != nil {
+			t.Fatalf("TryConvertTo[uint32](%q): %v", src, err)
+		}
+		if len(dst) != 1 || cap(dst) != 2 {
+			t.Errorf("TryConvertTo[uint32](%q): len, cap = %v, %v; want 1, 2", src, len(dst), cap(dst))
+		}
+	})
+}
+
+// TestTryConvertToOverflow verifies that TryConvertTo reports a result whose
+// capacity overflows int, as TryConvertAt does.
+func TestTryConvertToOverflow(t *testing.T) {
+	src := hugeSlice(math.MaxInt / 4)
+	srcBytes := uintptr(len(src)) * 8
+
+	b, err := unsafeslice.TryConvertTo[byte](src)
+	var cerr *unsafeslice.ConversionError
+	if !errors.As(err, &cerr) || cerr.Field != "capacity" || cerr.SrcBytes != srcBytes {
+		t.Fatalf("TryConvertTo[byte](hugeSlice) = %v, %#v; want *ConversionError for capacity of %d bytes", len(b), err, srcBytes)
+	}
+	if want := fmt.Sprintf("TryConvertTo: dst capacity (%d) overflows int", srcBytes); err.Error() != want {
+		t.Errorf("TryConvertTo[byte](hugeSlice) = %q; want %q", err, want)
+	}
+	if unsafeslice.CanConvertTo[byte](src) {
+		t.Errorf("CanConvertTo[byte](hugeSlice) = true; want false")
+	}
+}
+
+func TestCanConvertTo(t *testing.T) {
+	cases := []struct {
+		src  []byte
+		want bool
+	}{
+		{src: []byte("foobar")[:4:6], want: false},
+		{src: []byte("foobar\x00\x00")[:6], want: false},
+		{src: []byte("foobar\x00\x00")[:4], want: true},
+		{src: []byte{}, want: true},
+	}
+	for _, tc := range cases {
+		if got := unsafeslice.CanConvertTo[uint32](tc.src); got != tc.want {
+			t.Errorf("CanConvertTo[uint32](%q[:%d:%d]) = %v; want %v", tc.src, len(tc.src), cap(tc.src), got, tc.want)
+		}
+	}
+}
+
+func TestConvertedLen(t *testing.T) {
+	src := make([]uint16, 6, 10)
+	n, c, ok := unsafeslice.ConvertedLen[uint32](src)
+	if n != 3 || c != 5 || !ok {
+		t.Errorf("ConvertedLen[uint32](src) = %v, %v, %v; want 3, 5, true", n, c, ok)
+	}
+
+	n, c, ok = unsafeslice.ConvertedLen[uint64](src)
+	if n != 0 || c != 0 || ok {
+		t.Errorf("ConvertedLen[uint64](src) = %v, %v, %v; want 0, 0, false", n, c, ok)
+	}
+}
+
+func TestReinterpretLen(t *testing.T) {
+	const maxInt = int(^uint(0) >> 1)
+	for _, tc := range []struct {
+		desc string
+		f    func(int) (int, bool)
+		n    int
+		want int
+		ok   bool
+	}{
+		{"bytes to uint32", unsafeslice.ReinterpretLen[uint32, byte], 12, 3, true},
+		{"uint32 to bytes", unsafeslice.ReinterpretLen[byte, uint32], 3, 12, true},
+		{"uneven", unsafeslice.ReinterpretLen[uint64, uint32], 3, 0, false},
+		{"negative", unsafeslice.ReinterpretLen[byte, byte], -1, 0, false},
+		{"overflows int", unsafeslice.ReinterpretLen[byte, uint16], maxInt/2 + 1, 0, false},
+		{"overflows uintptr", unsafeslice.ReinterpretLen[uint64, [16]byte], maxInt, 0, false},
+		{"zero-size dst", unsafeslice.ReinterpretLen[struct{}, byte], 1, 0, false},
+	} {
+		if got, ok := tc.f(tc.n); got != tc.want || ok != tc.ok {
+			t.Errorf("%s: ReinterpretLen(%d) = %v, %v; want %v, %v", tc.desc, tc.n, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestConvertToAllocs(t *testing.T) {
+	src := make([]byte, 16)
+	var dst []uint32
+	avg := testing.AllocsPerRun(1000, func() {
+		dst = unsafeslice.ConvertTo[uint32](src)
+	})
+	if avg > 0 {
+		t.Errorf("ConvertTo made %v allocations; want 0", avg)
+	}
+	_ = dst
+}
+
+func TestBytesOf(t *testing.T) {
+	v := [4]byte{1, 2, 3, 4}
+	b := unsafeslice.BytesOf(&v)
+	if len(b) != len(v) || cap(b) != len(v) {
+		t.Fatalf("BytesOf(&%v): len, cap = %v, %v; want %v, %v", v, len(b), cap(b), len(v), len(v))
+	}
+	if &b[0] != &v[0] {
+		t.Errorf("BytesOf(&%v) does not alias its argument.", v)
+	}
+
+	x := struct {
+		a uint16
+		b uint64
+	}{}
+	if n := len(unsafeslice.BytesOf(&x)); uintptr(n) != unsafe.Sizeof(x) {
+		t.Errorf("len(BytesOf(&%T)) = %v; want %v", x, n, unsafe.Sizeof(x))
+	}
+}
+
+func TestZero(t *testing.T) {
+	key := []uint64{1, 2, 3}
+	unsafeslice.Zero(key[:2])
+	if key[0] != 0 || key[1] != 0 || key[2] != 3 {
+		t.Errorf("after Zero(key[:2]), key = %v; want [0 0 3]", key)
+	}
+
+	x := 1
+	ps := []*int{&x, &x}
+	unsafeslice.Zero(ps)
+	if ps[0] != nil || ps[1] != nil {
+		t.Errorf("after Zero(ps), ps = %v; want [<nil> <nil>]", ps)
+	}
+
+	unsafeslice.Zero([]byte(nil))
+}
+
+func TestRawBytes(t *testing.T) {
+	type record struct {
+		id    uint32
+		flags uint16
+	}
+	size := int(unsafe.Sizeof(record{}))
+
+	rs := make([]record, 2, 3)
+	rs[1] = record{id: 1, flags: 2}
+	b := unsafeslice.RawBytes(rs)
+	if len(b) != 2*size || cap(b) != 3*size {
+		t.Fatalf("RawBytes(rs): len, cap = %v, %v; want %v, %v", len(b), cap(b), 2*size, 3*size)
+	}
+	if unsafe.Pointer(&b[0]) != unsafe.Pointer(&rs[0]) {
+		t.Errorf("RawBytes(rs) does not alias its argument.")
+	}
+	if !bytes.Equal(b[size:], unsafeslice.BytesOf(&rs[1])) {
+		t.Errorf("RawBytes(rs)[%d:] = %x; want %x", size, b[size:], unsafeslice.BytesOf(&rs[1]))
+	}
+
+	if b := unsafeslice.RawBytes([]record(nil)); b != nil {
+		t.Errorf("RawBytes([]record(nil)) = %#v; want nil", b)
+	}
+}
+
+func ExampleSplitHeader() {
+	type header struct {
+		Kind, Flags uint8
+		Len         uint16
+	}
+
+	msg := unsafeslice.MakeAligned[header](3)
+	copy(msg, "\x01\x00\x00\x00payload")
+
+	h, payload := unsafeslice.SplitHeader[header](msg)
+	fmt.Printf("kind %d: %s\n", h.Kind, payload[:7])
+
+	// Output:
+	// kind 1: payload
+}
+
+func TestSplitHeaderErrors(t *testing.T) {
+	buf := unsafeslice.MakeAligned[uint32](2)
+	cases := []struct {
+		desc string
+		b    []byte
+	}{
+		{desc: "too short", b: buf[:3]},
+		{desc: "misaligned", b: buf[1:]},
+	}
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			defer func() {
+				if msg := recover(); msg != nil {
+					t.Logf("recovered: %v", msg)
+				} else {
+					t.Errorf("SplitHeader failed to panic as expected.")
+				}
+			}()
+			unsafeslice.SplitHeader[uint32](tc.b)
+		})
+	}
+
+	t.Run("contains pointers", func(t *testing.T) {
+		defer func() {
+			if msg := recover(); msg != nil {
+				t.Logf("recovered: %v", msg)
+			} else {
+				t.Errorf("SplitHeader failed to panic as expected.")
+			}
+		}()
+		unsafeslice.SplitHeader[struct{ p *int }](unsafeslice.MakeAligned[uintptr](2))
+	})
+}
+
+func TestReinterpretConsuming(t *testing.T) {
+	buf := unsafeslice.MakeAligned[uint32](2)
+	src := buf
+	u := unsafeslice.ReinterpretConsuming[uint32](&src)
+	if src != nil {
+		t.Errorf("ReinterpretConsuming[uint32](&src) left src = %v; want nil", src)
+	}
+	if len(u) != 2 || unsafe.Pointer(&u[0]) != unsafe.Pointer(&buf[0]) {
+		t.Errorf("ReinterpretConsuming[uint32](&src) = %v; want 2 elements aliasing src", u)
+	}
+
+	src = buf[:7]
+	func() {
+		defer func() {
+			if msg := recover(); msg != nil {
+				t.Logf("recovered: %v", msg)
+			} else {
+				t.Errorf("ReinterpretConsuming[uint32](&src) with len(src) = 7 failed to panic as expected.")
+			}
+		}()
+		unsafeslice.ReinterpretConsuming[uint32](&src)
+	}()
+	if len(src) != 7 {
+		t.Errorf("ReinterpretConsuming modified src after panicking: %v", src)
+	}
+}
+
+func TestReinterpretPtrSlice(t *testing.T) {
+	type A struct {
+		p *int
+		n int
+	}
+	type B struct {
+		s []byte
+	}
+
+	x, y := 1, 2
+	as := []A{{&x, 10}, {&y, 20}}
+	ps := unsafeslice.ReinterpretPtrSlice[struct {
+		q *int
+		m int
+	}](as)
+	if ps[1].q != &y || ps[1].m != 20 {
+		t.Errorf("ReinterpretPtrSlice(%v)[1] = %v; want {%p 20}", as, ps[1], &y)
+	}
+
+	uptrs := unsafeslice.ReinterpretPtrSlice[unsafe.Pointer]([]*int{&x, &y})
+	if uptrs[0] != unsafe.Pointer(&x) {
+		t.Errorf("ReinterpretPtrSlice[unsafe.Pointer]([]*int{&x, &y})[0] = %p; want %p", uptrs[0], &x)
+	}
+
+	for _, f := range []func(){
+		func() { unsafeslice.ReinterpretPtrSlice[[2]uintptr](as) },
+		func() { unsafeslice.ReinterpretPtrSlice[B](make([]A, 3)) },
+		func() { unsafeslice.ReinterpretPtrSlice[struct{ n, p *int }](as) },
+	} {
+		func() {
+			defer func() {
+				if msg := recover(); msg != nil {
+					t.Logf("recovered: %v", msg)
+				} else {
+					t.Errorf("ReinterpretPtrSlice with mismatched layouts failed to panic as expected.")
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestReinterpretPrefix(t *testing.T) {
+	buf := unsafeslice.MakeAligned[uint32](3)
+	for n := 0; n <= len(buf); n++ {
+		u := unsafeslice.ReinterpretPrefix[uint32](buf[:n:n])
+		if len(u) != n/4 || cap(u) != n/4 {
+			t.Errorf("ReinterpretPrefix[uint32](buf[:%d:%d]): len %d, cap %d; want %d, %d", n, n, len(u), cap(u), n/4, n/4)
+		}
+		if len(u) > 0 && unsafe.Pointer(&u[0]) != unsafe.Pointer(&buf[0]) {
+			t.Errorf("ReinterpretPrefix[uint32](buf[:%d:%d]) does not alias buf", n, n)
+		}
+	}
+
+	if u := unsafeslice.ReinterpretPrefix[uint32](buf[:5]); len(u) != 1 || cap(u) != 3 {
+		t.Errorf("ReinterpretPrefix[uint32](buf[:5]): len %d, cap %d; want 1, 3", len(u), cap(u))
+	}
+	if u := unsafeslice.ReinterpretPrefix[struct{}](buf); u != nil {
+		t.Errorf("ReinterpretPrefix[struct{}](buf) = %v; want nil", u)
+	}
+	if u := unsafeslice.ReinterpretPrefix[uint32](buf[1:9]); u != nil {
+		t.Errorf("ReinterpretPrefix[uint32](buf[1:9]) = %v; want nil", u)
+	}
+}
+
+func TestGrid(t *testing.T) {
+	backing := []int{0, 1, 2, 3, 4, 5}
+	grid := unsafeslice.Grid(backing, 2, 3)
+	if want := [][]int{{0, 1, 2}, {3, 4, 5}}; !reflect.DeepEqual(grid, want) {
+		t.Fatalf("Grid(%v, 2, 3) = %v; want %v", backing, grid, want)
+	}
+
+	grid[1][0] = 42
+	if backing[3] != 42 {
+		t.Errorf("write to grid[1][0] not visible in backing[3]: %v", backing)
+	}
+	_ = append(grid[0], -1)
+	if backing[3] != 42 {
+		t.Errorf("append to grid[0] overwrote grid[1]: %v", backing)
+	}
+
+	for _, dims := range [][2]int{{2, 2}, {-1, -6}, {3, 3}, {0, 6}, {6, 0}} {
+		func() {
+			defer func() {
+				if msg := recover(); msg != nil {
+					t.Logf("recovered: %v", msg)
+				} else {
+					t.Errorf("Grid(_, %d, %d) failed to panic as expected.", dims[0], dims[1])
+				}
+			}()
+			unsafeslice.Grid(backing, dims[0], dims[1])
+		}()
+	}
+
+	if grid := unsafeslice.Grid([]int{}, 4, 0); len(grid) != 4 || len(grid[3]) != 0 {
+		t.Errorf("Grid([]int{}, 4, 0) = %v; want 4 empty rows", grid)
+	}
+}
+
+func TestContainsPointers(t *testing.T) {
+	type noPointers struct {
+		a int32
+		b [4]float64
+		c struct{ d uintptr }
+	}
+	type withPointers struct {
+		a int32
+		b [1]string
+	}
+
+	check := func(name string, got, want bool) {
+		t.Helper()
+		if got != want {
+			t.Errorf("ContainsPointers[%s]() = %v; want %v", name, got, want)
+		}
+	}
+	check("int", unsafeslice.ContainsPointers[int](), false)
+	check("noPointers", unsafeslice.ContainsPointers[noPointers](), false)
+	check("[0]*int", unsafeslice.ContainsPointers[[0]*int](), false)
+	check("*int", unsafeslice.ContainsPointers[*int](), true)
+	check("unsafe.Pointer", unsafeslice.ContainsPointers[unsafe.Pointer](), true)
+	check("withPointers", unsafeslice.ContainsPointers[withPointers](), true)
+	check("[]byte", unsafeslice.ContainsPointers[[]byte](), true)
+	check("any", unsafeslice.ContainsPointers[any](), true)
+}
+
+func TestConvertToAll(t *testing.T) {
+	buf := unsafeslice.MakeAligned[float64](6)
+	cols := unsafeslice.ConvertToAll[float64](buf, []int{1, 0, 3, 2})
+	if len(cols) != 4 {
+		t.Fatalf("ConvertToAll returned %d columns; want 4", len(cols))
+	}
+	all := unsafeslice.ConvertTo[float64](buf)
+	for i, want := range [][]float64{all[0:1], all[1:1], all[1:4], all[4:6]} {
+		gotData, gotLen, gotCap := unsafeslice.Header(cols[i])
+		wantData, wantLen, _ := unsafeslice.Header(want)
+		if wantLen == 0 {
+			// An empty column need not point anywhere in particular.
+			gotData = wantData
+		}
+		if gotData != wantData || gotLen != wantLen || gotCap != wantLen {
+			t.Errorf("column %d = {%#x, %d, %d}; want {%#x, %d, %d}", i, gotData, gotLen, gotCap, wantData, wantLen, wantLen)
+		}
+	}
+
+	for _, lens := range [][]int{{1, 2}, {4, 3}, {7, -1}, {-1, 7}} {
+		func() {
+			defer func() {
+				if msg := recover(); msg != nil {
+					t.Logf("recovered: %v", msg)
+				} else {
+					t.Errorf("ConvertToAll[float64](buf, %v) failed to panic as expected.", lens)
+				}
+			}()
+			unsafeslice.ConvertToAll[float64](buf, lens)
+		}()
+	}
+
+	func() {
+		defer func() {
+			if msg := recover(); msg != nil {
+				t.Logf("recovered: %v", msg)
+			} else {
+				t.Errorf("ConvertToAll[float64](buf[1:41]) failed to panic as expected.")
+			}
+		}()
+		unsafeslice.ConvertToAll[float64](buf[1:41], []int{5})
+	}()
+
+	// Like ConvertTo, ConvertToAll does not require empty columns to be aligned.
+	if cols := unsafeslice.ConvertToAll[float64](buf[1:1], []int{0, 0}); len(cols) != 2 || len(cols[0])+len(cols[1]) != 0 {
+		t.Errorf("ConvertToAll[float64](buf[1:1], [0 0]) = %v; want 2 empty columns", cols)
+	}
+}
+
+func TestConvertAtAll(t *testing.T) {
+	buf := unsafeslice.MakeAligned[float64](4)
+	var (
+		fs    []float64
+		is    []int32
+		empty []uint16
+		bs    []byte
+	)
+	unsafeslice.ConvertAtAll(buf, []int{2, 3, 0, 4}, &fs, &is, &empty, &bs)
+
+	all := unsafeslice.ConvertTo[float64](buf)
+	if len(fs) != 2 || cap(fs) != 2 || &fs[0] != &all[0] {
+		t.Errorf("ConvertAtAll set fs to %v (cap %d); want an alias of the first 16 bytes", fs, cap(fs))
+	}
+	if len(is) != 3 || cap(is) != 3 || unsafe.Pointer(&is[0]) != unsafe.Pointer(&buf[16]) {
+		t.Errorf("ConvertAtAll set is to %v (cap %d); want an alias of bytes 16-28", is, cap(is))
+	}
+	if empty != nil {
+		t.Errorf("ConvertAtAll set empty to %v; want nil", empty)
+	}
+	if len(bs) != 4 || cap(bs) != 4 || &bs[0] != &buf[28] {
+		t.Errorf("ConvertAtAll set bs to %v (cap %d); want an alias of bytes 28-32", bs, cap(bs))
+	}
+
+	// An empty column need not be aligned, even for an element type that would
+	// require it.
+	var u64 []uint64
+	unsafeslice.ConvertAtAll(buf[1:4], []int{3, 0}, &bs, &u64)
+	if len(bs) != 3 || u64 != nil {
+		t.Errorf("ConvertAtAll(buf[1:4], [3 0]) set %v, %v; want 3 bytes and nil", bs, u64)
+	}
+
+	for _, tc := range []struct {
+		desc string
+		b    []byte
+		lens []int
+		dsts []interface{}
+	}{
+		{"misaligned", buf[1:9], []int{1}, []interface{}{&u64}},
+		{"misaligned column", buf[:12], []int{1, 1}, []interface{}{&bs, &u64}},
+		{"short", buf, []int{2, 3}, []interface{}{&fs, &is}},
+		{"long", buf, []int{4, 1}, []interface{}{&fs, &bs}},
+		{"negative", buf, []int{-1, 36}, []interface{}{&bs, &bs}},
+		{"mismatched lengths", buf, []int{4}, []interface{}{&fs, &bs}},
+		{"pointers", buf[:8], []int{1}, []interface{}{new([]*byte)}},
+		{"not a slice pointer", buf, []int{4}, []interface{}{fs}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			defer func() {
+				if msg := recover(); msg != nil {
+					t.Logf("recovered: %v", msg)
+				} else {
+					t.Errorf("ConvertAtAll with lengths %v failed to panic as expected.", tc.lens)
+				}
+			}()
+			unsafeslice.ConvertAtAll(tc.b, tc.lens, tc.dsts...)
+		})
+	}
+}
+
+func BenchmarkConvertToAll(b *testing.B) {
+	buf := unsafeslice.MakeAligned[int32](1 << 10)
+	lens := make([]int, 1<<8)
+	for i := range lens {
+		lens[i] = 4
+	}
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		unsafeslice.ConvertToAll[int32](buf, lens)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	buf := make([]uint16, 4, 8)
+	data, length, capacity := unsafeslice.Header(buf[1:3])
+	if want := uintptr(unsafe.Pointer(&buf[1])); data != want || length != 2 || capacity != 7 {
+		t.Errorf("Header(buf[1:3]) = %#x, %d, %d; want %#x, 2, 7", data, length, capacity, want)
+	}
+
+	if data, length, capacity := unsafeslice.Header([]byte(nil)); data != 0 || length != 0 || capacity != 0 {
+		t.Errorf("Header(nil) = %#x, %d, %d; want 0, 0, 0", data, length, capacity)
+	}
+}
+
+func TestDescribeSlice(t *testing.T) {
+	buf := make([]uint32, 4, 6)
+	want := fmt.Sprintf("[]uint32{data:%#x, len:4, cap:6, elemSize:4, bytes:16/24, aligned:true}", unsafe.Pointer(&buf[0]))
+	if got := unsafeslice.DescribeSlice(buf); got != want {
+		t.Errorf("DescribeSlice(buf) = %q; want %q", got, want)
+	}
+
+	b := unsafeslice.ConvertTo[byte](buf)[1:5]
+	want = fmt.Sprintf("[]uint8{data:%#x, len:4, cap:23, elemSize:1, bytes:4/23, aligned:true}", unsafe.Pointer(&b[0]))
+	if got := unsafeslice.DescribeSlice(b); got != want {
+		t.Errorf("DescribeSlice(b) = %q; want %q", got, want)
+	}
+
+	want = "[]uint64{data:0x0, len:0, cap:0, elemSize:8, bytes:0/0, aligned:true}"
+	if got := unsafeslice.DescribeSlice([]uint64(nil)); got != want {
+		t.Errorf("DescribeSlice(nil) = %q; want %q", got, want)
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	buf := unsafeslice.MakeAligned[uint32](4)
+	u32 := unsafeslice.ConvertTo[uint32](buf)
+
+	cases := []struct {
+		desc string
+		a    []byte
+		b    []uint32
+		want bool
+	}{
+		{"same memory", buf, u32, true},
+		{"suffix of a within b", buf[12:], u32[:1], true},
+		{"adjacent", buf[:4:4], u32[1:], false},
+		{"capacity overlaps", buf[:0:5], u32[1:], true},
+		{"empty a", buf[:0:0], u32, false},
+		{"nil b", buf, nil, false},
+		{"distinct allocations", make([]byte, 16), u32, false},
+	}
+	for _, tc := range cases {
+		if got := unsafeslice.Overlap(tc.a, tc.b); got != tc.want {
+			t.Errorf("%s: Overlap(a, b) = %v; want %v", tc.desc, got, tc.want)
+		}
+		if got := unsafeslice.Overlap(tc.b, tc.a); got != tc.want {
+			t.Errorf("%s: Overlap(b, a) = %v; want %v", tc.desc, got, tc.want)
+		}
+	}
+}
+
+func TestValueOf(t *testing.T) {
+	type point struct{ X, Y int32 }
+	v := &point{1, 2}
+	if p := unsafeslice.ValueOf[point](unsafeslice.BytesOf(v)); p != v {
+		t.Errorf("ValueOf(BytesOf(%p)) = %p; want %p", v, p, v)
+	}
+
+	buf := unsafeslice.MakeAligned[point](2)
+	for _, b := range [][]byte{buf[:7], buf[1:9]} {
+		func() {
+			defer func() {
+				if msg := recover(); msg != nil {
+					t.Logf("recovered: %v", msg)
+				} else {
+					t.Errorf("ValueOf[point] of %d bytes at %p failed to panic as expected.", len(b), b)
+				}
+			}()
+			unsafeslice.ValueOf[point](b)
+		}()
+	}
+
+	type node struct {
+		next *node
+		val  int
+	}
+	func() {
+		defer func() {
+			if msg := recover(); msg != nil {
+				t.Logf("recovered: %v", msg)
+			} else {
+				t.Errorf("ValueOf[node] failed to panic as expected.")
+			}
+		}()
+		unsafeslice.ValueOf[node](unsafeslice.MakeAligned[uint64](2))
+	}()
+}
+
+func TestPointerAt(t *testing.T) {
+	buf := unsafeslice.MakeAligned[uint32](4)
+	p := unsafeslice.PointerAt[uint32](buf, 8)
+	*p = 0xffffffff
+	if buf[7] != 0 || buf[8] != 0xff || buf[11] != 0xff || buf[12] != 0 {
+		t.Errorf("write through PointerAt[uint32](buf, 8) produced %x", buf)
+	}
+
+	for _, off := range []int{-4, 13, 16, 20, 2} {
+		func() {
+			defer func() {
+				if msg := recover(); msg != nil {
+					t.Logf("recovered: %v", msg)
+				} else {
+					t.Errorf("PointerAt[uint32](buf, %d) failed to panic as expected.", off)
+				}
+			}()
+			unsafeslice.PointerAt[uint32](buf, off)
+		}()
+	}
+
+	func() {
+		defer func() {
+			if msg := recover(); msg != nil {
+				t.Logf("recovered: %v", msg)
+			} else {
+				t.Errorf("PointerAt[*int](buf, 0) failed to panic as expected.")
+			}
+		}()
+		unsafeslice.PointerAt[*int](buf, 0)
+	}()
+}
+
+func TestAppendValue(t *testing.T) {
+	v := [4]byte{1, 2, 3, 4}
+	buf := unsafeslice.AppendValue([]byte{0}, &v)
+	if want := []byte{0, 1, 2, 3, 4}; string(buf) != string(want) {
+		t.Errorf("AppendValue([0], &%v) = %v; want %v", v, buf, want)
+	}
+
+	buf = make([]byte, 0, 8)
+	avg := testing.AllocsPerRun(100, func() {
+		buf = unsafeslice.AppendValue(buf[:0], &v)
+	})
+	if avg > 0 {
+		t.Errorf("AppendValue made %v allocations with sufficient capacity; want 0", avg)
+	}
+}
+
+func TestAsArray(t *testing.T) {
+	key := make([]byte, 40)
+	if p := unsafeslice.AsArray32(key); &p[0] != &key[0] {
+		t.Errorf("AsArray32(key) does not alias key.")
+	}
+	if p := unsafeslice.AsArray4(key[36:]); &p[3] != &key[39] {
+		t.Errorf("AsArray4(key[36:]) does not alias key[36:].")
+	}
+	if p := unsafeslice.AsArray8(key[32:]); &p[0] != &key[32] || &p[7] != &key[39] {
+		t.Errorf("AsArray8(key[32:]) does not alias key[32:].")
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("AsArray16 failed to panic as expected.")
+		}
+	}()
+	unsafeslice.AsArray16(key[:15])
+}
+
+func TestMakeAligned(t *testing.T) {
+	b := unsafeslice.MakeAligned[uint64](3)
+	if len(b) != 24 {
+		t.Errorf("len(MakeAligned[uint64](3)) = %v; want 24", len(b))
+	}
+	if !unsafeslice.Aligned[uint64](unsafe.Pointer(&b[0])) {
+		t.Errorf("MakeAligned[uint64](3) returned data at %p, which is not aligned for uint64", &b[0])
+	}
+	_ = unsafeslice.Reinterpret[uint64](b)
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+			if s, _ := msg.(string); !strings.Contains(s, "MakeAligned") {
+				t.Errorf("MakeAligned[*int](1) panicked with %q; want a message naming MakeAligned", msg)
+			}
+		} else {
+			t.Errorf("MakeAligned[*int](1) failed to panic as expected.")
+		}
+	}()
+	unsafeslice.MakeAligned[*int](1)
+}
+
+func TestSliceOfString(t *testing.T) {
+	buf := []uint32{1, 2, 3}
+	s := unsafeslice.AsString(unsafeslice.ConvertTo[byte](buf))
+
+	got := unsafeslice.SliceOfString[uint32](s)
+	if len(got) != len(buf) || (&got[0] != &buf[0] && !paranoidEnabled) {
+		t.Errorf("SliceOfString[uint32](%q) = %v; want alias of %v", s, got, buf)
+	}
+
+	t.Run("incompatible length", func(t *testing.T) {
+		defer func() {
+			if msg := recover(); msg != nil {
+				t.Logf("recovered: %v", msg)
+			} else {
+				t.Errorf("SliceOfString failed to panic as expected.")
+			}
+		}()
+		unsafeslice.SliceOfString[uint32](s[:5])
+	})
+
+	t.Run("misaligned data", func(t *testing.T) {
+		defer func() {
+			if msg := recover(); msg != nil {
+				t.Logf("recovered: %v", msg)
+			} else {
+				t.Errorf("SliceOfString failed to panic as expected.")
+			}
+		}()
+		unsafeslice.SliceOfString[uint32](s[1:9])
+	})
+
+	t.Run("huge length", func(t *testing.T) {
+		defer func() {
+			if msg := recover(); msg != nil {
+				t.Logf("recovered: %v", msg)
+			} else {
+				t.Errorf("SliceOfString failed to panic as expected.")
+			}
+		}()
+
+		// Synthesize a header whose data would wrap around the end of the address
+		// space. SliceOfString must reject it without reading any of the data.
+		var huge string
+		hdr := (*reflect.StringHeader)(unsafe.Pointer(&huge))
+		hdr.Data = ^uintptr(0) &^ 0xfff
+		hdr.Len = math.MaxInt &^ 3
+		unsafeslice.SliceOfString[uint32](huge)
+	})
+}
+
+func TestStringOfSlice(t *testing.T) {
+	buf := []uint32{1, 2, 3}
+	s := unsafeslice.StringOfSlice(buf)
+	if len(s) != 12 {
+		t.Errorf("len(StringOfSlice(%v)) = %v; want 12", buf, len(s))
+	}
+	if paranoidEnabled {
+		return // AsString and OfString make copies instead of aliasing.
+	}
+	if b := unsafeslice.OfString(s); unsafe.Pointer(&b[0]) != unsafe.Pointer(&buf[0]) {
+		t.Errorf("StringOfSlice(%v) does not alias its argument.", buf)
+	}
+}
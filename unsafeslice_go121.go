@@ -0,0 +1,56 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+// +build go1.21
+
+package unsafeslice
+
+import (
+	"fmt"
+	"runtime"
+	"unsafe"
+)
+
+// AsStringPinned is like AsString, but also pins the array backing b in memory
+// until the returned release function is called.
+//
+// The caller must ensure that the contents of b are never again mutated, and
+// that the returned string is not used after release has been called unless b
+// is otherwise kept reachable.
+//
+// The caller must also call release exactly once. Dropping it without calling
+// it leaks the pin, and the runtime panics when it collects the leaked pin's
+// runtime.Pinner.
+func AsStringPinned(b []byte) (s string, release func()) {
+	var p runtime.Pinner
+	if len(b) > 0 {
+		p.Pin(&b[0])
+	}
+	return AsString(b), p.Unpin
+}
+
+// SetAtPinned is like SetAtForeign, but allows T to contain pointers, provided
+// that every Go pointer stored in the slice is pinned by pinner.
+//
+// SetAtPinned pins the memory at p with pinner, which has no effect if p is not
+// managed by the Go runtime, such as memory allocated by C.malloc. The garbage
+// collector does not scan such memory, so before storing a Go pointer in any
+// element of *dst the caller must pin the object to which it points using the
+// same pinner, and must not call pinner.Unpin while any element of *dst still
+// holds such a pointer. That applies to every pointer within T, including the
+// data pointers of any strings or slices and the values of any interfaces.
+func SetAtPinned[T any](dst *[]T, p unsafe.Pointer, n int, pinner *runtime.Pinner) {
+	if pinner == nil {
+		panic("SetAtPinned with nil pinner")
+	}
+	if n < 0 {
+		panic(fmt.Sprintf("SetAtPinned with negative length %d", n))
+	}
+	checkAddrRange("SetAtPinned", p, n, unsafe.Sizeof(*new(T)))
+	if n > 0 {
+		pinner.Pin(p)
+	}
+	*dst = unsafe.Slice((*T)(p), n)
+}
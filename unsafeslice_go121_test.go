@@ -0,0 +1,57 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+// +build go1.21
+
+package unsafeslice_test
+
+import (
+	"runtime"
+	"testing"
+	"unsafe"
+
+	"github.com/bcmills/unsafeslice"
+)
+
+func TestAsStringPinned(t *testing.T) {
+	b := []byte("Hello, world!")
+	s, release := unsafeslice.AsStringPinned(b)
+	if s != "Hello, world!" {
+		t.Errorf("AsStringPinned(%q) = %q", b, s)
+	}
+	release()
+
+	_, release = unsafeslice.AsStringPinned(nil)
+	release()
+}
+
+func TestSetAtPinned(t *testing.T) {
+	// Stand in for foreign memory with a Go array, as in TestSetAtForeign.
+	var buf [2]*int
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+
+	var s []*int
+	unsafeslice.SetAtPinned(&s, unsafe.Pointer(&buf[0]), len(buf), &pinner)
+	if len(s) != len(buf) || &s[0] != &buf[0] {
+		t.Fatalf("SetAtPinned(_, %p, %d, _) set %v; want alias of buf", &buf[0], len(buf), s)
+	}
+
+	x := new(int)
+	pinner.Pin(x)
+	s[1] = x
+	if buf[1] != x {
+		t.Errorf("store through SetAtPinned slice not visible in buf")
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("SetAtPinned with nil pinner failed to panic as expected.")
+		}
+	}()
+	unsafeslice.SetAtPinned(&s, unsafe.Pointer(&buf[0]), len(buf), nil)
+}
@@ -0,0 +1,46 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.23
+// +build go1.23
+
+package unsafeslice
+
+import (
+	"fmt"
+	"iter"
+	"unsafe"
+)
+
+// Strided returns an iterator over pointers to n values of type T located at
+// base, base+stride, base+2*stride, and so on. It yields the index of each
+// value along with the pointer.
+//
+// Strided is intended for reading one field from each of an array of C
+// structs without copying the array: base points to the field in the first
+// struct, and stride is the size of the struct.
+//
+// Strided panics if n is negative, if stride is less than the size of T or is
+// not a multiple of its alignment, or if the region would extend past the end
+// of the address space. As with SliceAt, the caller must ensure that base is
+// suitably aligned for T and that the memory remains valid for as long as the
+// iterator and its pointers are in use.
+func Strided[T any](base unsafe.Pointer, n, stride int) iter.Seq2[int, *T] {
+	size, align := unsafe.Sizeof(*new(T)), unsafe.Alignof(*new(T))
+	if n < 0 {
+		panic(fmt.Sprintf("Strided with negative length %d", n))
+	}
+	if stride < 0 || uintptr(stride) < size || uintptr(stride)%align != 0 {
+		panic(fmt.Sprintf("Strided with stride %d; need a multiple of %d that is at least %d", stride, align, size))
+	}
+	checkAddrRange("Strided", base, n, uintptr(stride))
+
+	return func(yield func(int, *T) bool) {
+		for i := 0; i < n; i++ {
+			if !yield(i, (*T)(unsafe.Add(base, i*stride))) {
+				return
+			}
+		}
+	}
+}
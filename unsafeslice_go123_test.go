@@ -0,0 +1,64 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.23
+// +build go1.23
+
+package unsafeslice_test
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/bcmills/unsafeslice"
+)
+
+func TestStrided(t *testing.T) {
+	type record struct {
+		id    uint32
+		score float32
+		name  [8]byte
+	}
+	recs := []record{{id: 1, score: 0.5}, {id: 2, score: 1.5}, {id: 3, score: 2.5}}
+
+	base := unsafe.Pointer(&recs[0].score)
+	stride := int(unsafe.Sizeof(record{}))
+	var scores []float32
+	for i, p := range unsafeslice.Strided[float32](base, len(recs), stride) {
+		if p != &recs[i].score {
+			t.Errorf("Strided yielded %p at index %d; want %p", p, i, &recs[i].score)
+		}
+		scores = append(scores, *p)
+	}
+	if len(scores) != 3 || scores[0] != 0.5 || scores[1] != 1.5 || scores[2] != 2.5 {
+		t.Errorf("scores from Strided = %v; want [0.5 1.5 2.5]", scores)
+	}
+
+	for i := range unsafeslice.Strided[float32](base, len(recs), stride) {
+		if i > 0 {
+			t.Fatalf("Strided continued after break")
+		}
+		break
+	}
+
+	for _, tc := range []struct {
+		desc      string
+		n, stride int
+	}{
+		{"negative length", -1, stride},
+		{"stride smaller than element", 2, 2},
+		{"misaligned stride", 2, 6},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			defer func() {
+				if msg := recover(); msg != nil {
+					t.Logf("recovered: %v", msg)
+				} else {
+					t.Errorf("Strided(_, %d, %d) failed to panic as expected.", tc.n, tc.stride)
+				}
+			}()
+			unsafeslice.Strided[float32](base, tc.n, tc.stride)
+		})
+	}
+}
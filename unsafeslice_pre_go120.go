@@ -0,0 +1,99 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !go1.20
+// +build !go1.20
+
+package unsafeslice
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// This file contains implementations of OfString and AsString for toolchains
+// that lack unsafe.String and unsafe.StringData.
+
+// OfString returns a slice that refers to the data backing the string s.
+//
+// The caller must ensure that the contents of the slice are never mutated.
+//
+// Programs that use OfString should be tested under the race detector to flag
+// erroneous mutations.
+//
+// Programs that have been adequately tested and shown to be safe may be
+// recompiled with the "unsafe" tag to significantly reduce the overhead of this
+// function, at the cost of reduced safety checks. Programs built under the race
+// detector always have safety checks enabled, even when the "unsafe" tag is
+// set.
+//
+// Programs built with the "paranoid" tag instead receive a defensive copy,
+// which is compared against the original at every garbage collection. This
+// makes mutations deterministic to detect, for example in fuzz tests.
+func OfString(s string) []byte {
+	if paranoidEnabled && SafetyChecksEnabled() {
+		return paranoidOfString(s)
+	}
+
+	b := stringBytes(s)
+	maybeDetectMutations(b)
+	return b
+}
+
+// stringBytes returns a slice that refers to the data backing s, without any
+// mutation checks.
+func stringBytes(s string) []byte {
+	var b []byte
+	hdr := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	hdr.Data = uintptr(DataOfString(s))
+	hdr.Cap = len(s)
+	hdr.Len = len(s)
+	return b
+}
+
+// AsString returns a string that refers to the data backing the slice s.
+//
+// The caller must ensure that the contents of the slice are never again
+// mutated, and that its memory either is managed by the Go garbage collector or
+// remains valid for the remainder of this process's lifetime.
+//
+// Programs that use AsString should be tested under the race detector to flag
+// erroneous mutations.
+//
+// Programs that have been adequately tested and shown to be safe may be
+// recompiled with the "unsafe" tag to significantly reduce the overhead of this
+// function, at the cost of reduced safety checks. Programs built under the race
+// detector always have safety checks enabled, even when the "unsafe" tag is
+// set.
+//
+// Programs built with the "paranoid" tag instead receive a defensive copy,
+// which is compared against the original at every garbage collection. This
+// makes mutations deterministic to detect, for example in fuzz tests.
+func AsString(b []byte) string {
+	if paranoidEnabled && SafetyChecksEnabled() {
+		return paranoidAsString(b)
+	}
+
+	s := bytesString(b)
+	maybeDetectMutations(b)
+	return s
+}
+
+// bytesString returns a string that refers to the data backing b, without any
+// mutation checks.
+func bytesString(b []byte) string {
+	p := unsafe.Pointer((*reflect.SliceHeader)(unsafe.Pointer(&b)).Data)
+
+	var s string
+	hdr := (*reflect.StringHeader)(unsafe.Pointer(&s))
+	hdr.Data = uintptr(p)
+	hdr.Len = len(b)
+	return s
+}
+
+// DataOfString returns a pointer to the data backing s. The caller must not
+// write through the returned pointer.
+func DataOfString(s string) unsafe.Pointer {
+	return unsafe.Pointer((*reflect.StringHeader)(unsafe.Pointer(&s)).Data)
+}
@@ -5,11 +5,16 @@
 package unsafeslice_test
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"hash"
 	"hash/fnv"
 	"io"
+	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 	"unsafe"
 
@@ -80,6 +85,76 @@ func TestSetAtWithVeryLargeTypeDoesNotPanic(t *testing.T) {
 	unsafeslice.SetAt(&s, unsafe.Pointer(&x), 1)
 }
 
+func TestSetAtEmpty(t *testing.T) {
+	var x uint32
+	s := []uint32{1, 2, 3}
+	unsafeslice.SetAt(&s, unsafe.Pointer(&x), 0)
+	if s != nil {
+		t.Errorf("SetAt(_, %p, 0) set %v; want nil", &x, s)
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("SetAt with negative length failed to panic as expected.")
+		}
+	}()
+	unsafeslice.SetAt(&s, unsafe.Pointer(&x), -1)
+}
+
+func TestSetAtCap(t *testing.T) {
+	buf := make([]uint32, 8)
+	var s []uint32
+	unsafeslice.SetAtCap(&s, unsafe.Pointer(&buf[0]), 2, 8)
+	if len(s) != 2 || cap(s) != 8 || &s[0] != &buf[0] {
+		t.Errorf("SetAtCap(_, %p, 2, 8) set %v (cap %d); want alias of %v (cap 8)", &buf[0], s, cap(s), buf[:2])
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("SetAtCap with length greater than capacity failed to panic as expected.")
+		}
+	}()
+	unsafeslice.SetAtCap(&s, unsafe.Pointer(&buf[0]), 3, 2)
+}
+
+func TestSetAtAddressOverflow(t *testing.T) {
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("SetAt failed to panic as expected.")
+		}
+	}()
+
+	var x uint64
+	var s []uint64
+	unsafeslice.SetAt(&s, unsafe.Pointer(&x), int(^uint(0)>>1))
+}
+
+func TestAlignedAt(t *testing.T) {
+	buf := make([]uint64, 2)
+	p := unsafe.Pointer(&buf[0])
+
+	var u64 []uint64
+	if !unsafeslice.AlignedAt(&u64, p) {
+		t.Errorf("AlignedAt(*[]uint64, %p) = false; want true", p)
+	}
+
+	q := unsafe.Pointer(uintptr(p) + 1)
+	if unsafeslice.AlignedAt(&u64, q) {
+		t.Errorf("AlignedAt(*[]uint64, %p) = true; want false", q)
+	}
+
+	var b []byte
+	if !unsafeslice.AlignedAt(&b, q) {
+		t.Errorf("AlignedAt(*[]byte, %p) = false; want true", q)
+	}
+}
+
 func TestConvertAt(t *testing.T) {
 	u32 := []uint32{0x00102030, 0x40506070}[:1]
 	var b []byte
@@ -93,6 +168,16 @@ func TestConvertAt(t *testing.T) {
 	}
 }
 
+func TestConvertAtReadOnly(t *testing.T) {
+	u32 := []uint32{0x00102030, 0x40506070}
+	var b []byte
+	unsafeslice.ConvertAtReadOnly(&b, u32)
+
+	if len(b) != 8 || unsafe.Pointer(&b[0]) != unsafe.Pointer(&u32[0]) {
+		t.Errorf("ConvertAtReadOnly(_, %x) = %x; want an alias of length 8", u32, b)
+	}
+}
+
 func TestConvertAtErrors(t *testing.T) {
 	cases := []struct {
 		desc     string
@@ -108,6 +193,21 @@ func TestConvertAtErrors(t *testing.T) {
 			src:  []byte("foobar\x00\x00")[:6],
 			dst:  new([]uint32),
 		},
+		{
+			desc: "misaligned data",
+			src:  make([]byte, 17)[1:],
+			dst:  new([]uint64),
+		},
+		{
+			desc: "pointers from non-pointer data",
+			src:  make([]uintptr, 2),
+			dst:  new([]*byte),
+		},
+		{
+			desc: "pointers at different offsets",
+			src:  []string{"a", "b"},
+			dst:  new([]*int),
+		},
 	}
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
@@ -124,6 +224,244 @@ func TestConvertAtErrors(t *testing.T) {
 	}
 }
 
+func TestTryConvertAt(t *testing.T) {
+	orig := []uint32{1, 2, 3}
+	dst := orig
+
+	src := []byte("foobar")
+	err := unsafeslice.TryConvertAt(&dst, src)
+	var cerr *unsafeslice.ConversionError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("TryConvertAt(_, %q) = %v; want *ConversionError", src, err)
+	}
+	t.Logf("TryConvertAt(_, %q): %v", src, err)
+	if cerr.SrcBytes != 6 || cerr.DstElemSize != 4 {
+		t.Errorf("TryConvertAt(_, %q): SrcBytes = %d, DstElemSize = %d; want 6, 4", src, cerr.SrcBytes, cerr.DstElemSize)
+	}
+	if len(dst) != len(orig) || &dst[0] != &orig[0] {
+		t.Errorf("TryConvertAt modified dst after returning an error.")
+	}
+
+	var aligned []byte
+	unsafeslice.ConvertAt(&aligned, make([]uint64, 2))
+	misaligned := aligned[1:9:9]
+	var u64 []uint64
+	err = unsafeslice.TryConvertAt(&u64, misaligned)
+	var aerr *unsafeslice.AlignmentError
+	if !errors.As(err, &aerr) {
+		t.Fatalf("TryConvertAt(*[]uint64, misaligned) = %v; want *AlignmentError", err)
+	}
+	t.Logf("TryConvertAt(*[]uint64, misaligned): %v", err)
+	if want := unsafe.Alignof(uint64(0)); aerr.Align != want {
+		t.Errorf("TryConvertAt(*[]uint64, misaligned): Align = %d; want %d", aerr.Align, want)
+	}
+
+	var empty []struct{}
+	err = unsafeslice.TryConvertAt(&empty, src)
+	if !errors.As(err, &cerr) {
+		t.Fatalf("TryConvertAt(*[]struct{}, %q) = %v; want *ConversionError", src, err)
+	}
+	t.Logf("TryConvertAt(*[]struct{}, %q): %v", src, err)
+	if cerr.DstElemSize != 0 {
+		t.Errorf("TryConvertAt(*[]struct{}, %q): DstElemSize = %d; want 0", src, cerr.DstElemSize)
+	}
+
+	src = []byte("foobar\x00\x00")
+	if err := unsafeslice.TryConvertAt(&dst, src); err != nil {
+		t.Fatalf("TryConvertAt(_, %q) = %v", src, err)
+	}
+	if len(dst) != 2 {
+		t.Errorf("TryConvertAt(_, %q): length = %v; want 2", src, len(dst))
+	}
+}
+
+func TestConvertAtLen(t *testing.T) {
+	var aligned []byte
+	unsafeslice.ConvertAt(&aligned, make([]uint32, 4))
+
+	// The capacity of src (14 bytes) is not a multiple of 4, but its length is.
+	src := aligned[:8:14]
+	var u32 []uint32
+	unsafeslice.ConvertAtLen(&u32, src)
+	if len(u32) != 2 || cap(u32) != 2 || unsafe.Pointer(&u32[0]) != unsafe.Pointer(&src[0]) {
+		t.Errorf("ConvertAtLen(_, aligned[:8:14]) set %v (cap %d); want an alias of length and capacity 2", u32, cap(u32))
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("ConvertAtLen with src length not a multiple of 4 failed to panic as expected.")
+		}
+	}()
+	unsafeslice.ConvertAtLen(&u32, aligned[:6:14])
+}
+
+// hugeSlice returns a []uint64 of length and capacity n whose data is a single
+// uint64, for testing conversions that must fail before touching the data.
+func hugeSlice(n int) []uint64 {
+	var x uint64
+	var s []uint64
+	hdr := (*reflect.SliceHeader)(unsafe.Pointer(&s))
+	hdr.Data = uintptr(unsafe.Pointer(&x))
+	hdr.Len = n
+	hdr.Cap = n
+	return s
+}
+
+func TestConvertAtOverflow(t *testing.T) {
+	// The size of src in bytes fits in a uintptr, but the number of bytes, and
+	// hence the length and capacity of a []byte, exceeds the maximum int on both
+	// 32-bit and 64-bit platforms.
+	const maxInt = int(^uint(0) >> 1)
+	src := hugeSlice(maxInt / 4)
+	srcBytes := uintptr(len(src)) * 8
+
+	var b []byte
+	err := unsafeslice.TryConvertAt(&b, src)
+	var cerr *unsafeslice.ConversionError
+	if !errors.As(err, &cerr) || cerr.Field != "capacity" || cerr.SrcBytes != srcBytes {
+		t.Fatalf("TryConvertAt(*[]byte, hugeSlice) = %#v; want *ConversionError for capacity of %d bytes", err, srcBytes)
+	}
+	if want := fmt.Sprintf("TryConvertAt: dst capacity (%d) overflows int", srcBytes); err.Error() != want {
+		t.Errorf("TryConvertAt(*[]byte, hugeSlice) = %q; want %q", err, want)
+	}
+	if b != nil {
+		t.Errorf("TryConvertAt modified dst after returning an error.")
+	}
+
+	// ConvertAtOrCopy falls back to a copy when the capacity does not fit, so it
+	// reaches the check on the length instead.
+	for _, tc := range []struct {
+		desc string
+		f    func()
+		want string
+	}{
+		{
+			desc: "ConvertAt",
+			f:    func() { unsafeslice.ConvertAt(&b, src) },
+			want: fmt.Sprintf("ConvertAt: dst capacity (%d) overflows int", srcBytes),
+		},
+		{
+			desc: "ConvertAtOrCopy",
+			f:    func() { unsafeslice.ConvertAtOrCopy(&b, src) },
+			want: fmt.Sprintf("ConvertAtOrCopy: dst length (%d) overflows int", srcBytes),
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			defer func() {
+				if msg := recover(); msg != tc.want {
+					t.Errorf("recovered %v; want %q", msg, tc.want)
+				}
+			}()
+			tc.f()
+		})
+	}
+}
+
+func TestConvertAtArray(t *testing.T) {
+	src := []byte("\x01\x00\x02\x00\x03\x00")
+	var want [3]uint16
+	binary.Read(bytes.NewReader(src), unsafeslice.NativeByteOrder(), &want)
+
+	// The data is deliberately misaligned: arrays are copied, not aliased.
+	var got [3]uint16
+	unsafeslice.ConvertAt(&got, append([]byte{0}, src...)[1:])
+	if got != want {
+		t.Errorf("ConvertAt(*[3]uint16, %q) = %v; want %v", src, got, want)
+	}
+
+	var short [4]uint16
+	err := unsafeslice.TryConvertAt(&short, src)
+	var cerr *unsafeslice.ConversionError
+	if !errors.As(err, &cerr) || cerr.Field != "array" {
+		t.Errorf("TryConvertAt(*[4]uint16, %q) = %v; want *ConversionError with Field \"array\"", src, err)
+	}
+	t.Logf("TryConvertAt(*[4]uint16, %q): %v", src, err)
+	if short != ([4]uint16{}) {
+		t.Errorf("TryConvertAt modified dst after returning an error: %v", short)
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("ConvertAt(*[1]*byte, []byte) failed to panic as expected.")
+		}
+	}()
+	var ptrs [1]*byte
+	unsafeslice.ConvertAt(&ptrs, make([]byte, unsafe.Sizeof(ptrs)))
+}
+
+func TestConvertAtOrCopy(t *testing.T) {
+	var aligned []byte
+	unsafeslice.ConvertAt(&aligned, make([]uint64, 3))
+	copy(aligned, "0123456789abcdefghijklmn")
+
+	var u64 []uint64
+	if copied := unsafeslice.ConvertAtOrCopy(&u64, aligned[:16]); copied {
+		t.Errorf("ConvertAtOrCopy(_, aligned[:16]) copied; want alias")
+	} else if &u64[0] != (*uint64)(unsafe.Pointer(&aligned[0])) {
+		t.Errorf("ConvertAtOrCopy(_, aligned[:16]) did not alias src")
+	}
+
+	for _, src := range [][]byte{aligned[1:17], aligned[:16:17]} {
+		u64 = nil
+		if copied := unsafeslice.ConvertAtOrCopy(&u64, src); !copied {
+			t.Errorf("ConvertAtOrCopy(_, %q) did not copy", src)
+			continue
+		}
+		var got []byte
+		unsafeslice.ConvertAt(&got, u64)
+		if string(got) != string(src) || len(u64) != 2 || cap(u64) != 2 {
+			t.Errorf("ConvertAtOrCopy(_, %q) = %q (cap %d); want a copy of src", src, got, cap(u64))
+		}
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("ConvertAtOrCopy with src length not a multiple of 8 failed to panic as expected.")
+		}
+	}()
+	unsafeslice.ConvertAtOrCopy(&u64, aligned[1:16])
+}
+
+func TestConvertBytes(t *testing.T) {
+	b := make([]byte, 12, 18)
+	n, m, p, err := unsafeslice.ConvertBytes(b, 6)
+	if err != nil || n != 2 || m != 3 || p != unsafe.Pointer(&b[0]) {
+		t.Errorf("ConvertBytes(b, 6) = %v, %v, %p, %v; want 2, 3, %p, <nil>", n, m, p, err, &b[0])
+	}
+
+	var rows [][6]byte
+	unsafeslice.SetAtCap(&rows, p, n, m)
+	rows[1][0] = 1
+	if b[6] != 1 {
+		t.Errorf("slice built from ConvertBytes(b, 6) does not alias b")
+	}
+
+	for _, src := range [][]byte{b[:7], b[:12:13]} {
+		_, _, _, err := unsafeslice.ConvertBytes(src, 6)
+		var cerr *unsafeslice.ConversionError
+		if !errors.As(err, &cerr) || cerr.DstElem != nil || cerr.DstElemSize != 6 {
+			t.Errorf("ConvertBytes(b[:%d:%d], 6) = %#v; want *ConversionError with DstElemSize 6", len(src), cap(src), err)
+		} else {
+			t.Logf("ConvertBytes(b[:%d:%d], 6): %v", len(src), cap(src), err)
+		}
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			t.Logf("recovered: %v", msg)
+		} else {
+			t.Errorf("ConvertBytes with zero element size failed to panic as expected.")
+		}
+	}()
+	unsafeslice.ConvertBytes(b, 0)
+}
+
 func ExampleOfString() {
 	s := "Hello, world!"
 
@@ -143,6 +481,75 @@ func ExampleOfString() {
 	// 38d1334144987bf4
 }
 
+func TestOfStringAt(t *testing.T) {
+	s := "Hello, world!"
+	b := unsafeslice.OfStringAt(s, 7, 12)
+	if string(b) != "world" || len(b) != cap(b) {
+		t.Errorf("OfStringAt(%q, 7, 12) = %q (cap %d); want %q (cap 5)", s, b, cap(b), "world")
+	}
+
+	for _, w := range [][2]int{{-1, 3}, {4, 3}, {0, len(s) + 1}} {
+		func() {
+			defer func() {
+				if msg := recover(); msg != nil {
+					t.Logf("recovered: %v", msg)
+				} else {
+					t.Errorf("OfStringAt(%q, %d, %d) failed to panic as expected.", s, w[0], w[1])
+				}
+			}()
+			unsafeslice.OfStringAt(s, w[0], w[1])
+		}()
+	}
+}
+
+func TestOfStrings(t *testing.T) {
+	ss := []string{"Hello", "", ", ", "world!"}
+	bs := unsafeslice.OfStrings(ss)
+	if len(bs) != len(ss) {
+		t.Fatalf("OfStrings(%q) returned %d slices; want %d", ss, len(bs), len(ss))
+	}
+	for i, b := range bs {
+		if string(b) != ss[i] {
+			t.Errorf("OfStrings(%q)[%d] = %q; want %q", ss, i, b, ss[i])
+		}
+	}
+}
+
+func TestRoundTripString(t *testing.T) {
+	b := []byte("Hello, world!")
+	before := unsafeslice.PendingCheckCount()
+	s, back := unsafeslice.RoundTripString(b)
+	if s != "Hello, world!" || string(back) != s {
+		t.Errorf("RoundTripString(%q) = %q, %q", b, s, back)
+	}
+	if data, _ := unsafeslice.StringHeader(s); !paranoidEnabled && (data != uintptr(unsafe.Pointer(&b[0])) || &back[0] != &b[0]) {
+		t.Errorf("RoundTripString(b) returned views that do not alias b")
+	}
+	if n := unsafeslice.PendingCheckCount(); safetyChecksEnabled && !paranoidEnabled && n > before+1 {
+		t.Errorf("RoundTripString started %d mutation checks; want 1", n-before)
+	}
+}
+
+func TestOfStringBuilder(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("Hello, ")
+	b := unsafeslice.OfStringBuilder(&sb)
+	if string(b) != "Hello, " {
+		t.Fatalf("OfStringBuilder(&sb) = %q; want %q", b, "Hello, ")
+	}
+	data, _ := unsafeslice.StringHeader(sb.String())
+	if !paranoidEnabled && uintptr(unsafe.Pointer(&b[0])) != data {
+		t.Errorf("OfStringBuilder(&sb) does not alias the builder's buffer")
+	}
+
+	// Writes to the builder append after the viewed bytes or move them to a new
+	// buffer, so the view is unchanged.
+	sb.WriteString(strings.Repeat("world!", 100))
+	if string(b) != "Hello, " {
+		t.Errorf("after further writes to sb, OfStringBuilder(&sb) = %q; want %q", b, "Hello, ")
+	}
+}
+
 func ExampleAsString() {
 	const input = "Hello, world!"
 	h := fnv.New64a()
@@ -159,7 +566,94 @@ func ExampleAsString() {
 	// 38d1334144987bf4
 }
 
+func TestStringHeader(t *testing.T) {
+	b := []byte("Hello, world!")
+	s := unsafeslice.AsString(b)
+	data, length := unsafeslice.StringHeader(s)
+	if length != len(b) {
+		t.Errorf("StringHeader(AsString(b)) length = %d; want %d", length, len(b))
+	}
+	if want := uintptr(unsafe.Pointer(&b[0])); !paranoidEnabled && data != want {
+		t.Errorf("StringHeader(AsString(b)) data = %#x; want %#x", data, want)
+	}
+
+	if data, length := unsafeslice.StringHeader(s[7:]); data != uintptr(unsafeslice.DataOfString(s))+7 || length != 6 {
+		t.Errorf("StringHeader(s[7:]) = %#x, %d; want %#x, 6", data, length, uintptr(unsafeslice.DataOfString(s))+7)
+	}
+}
+
+func ExampleFreeze() {
+	table := unsafeslice.Freeze([]byte("0123456789abcdef"))
+
+	// table may now be shared freely, but must never be mutated.
+	fmt.Printf("%c%c\n", table[0xb], table[0xe])
+
+	// Output:
+	// be
+}
+
+func TestNativeByteOrder(t *testing.T) {
+	u := []uint32{0x01020304}
+	var b []byte
+	unsafeslice.ConvertAt(&b, u)
+
+	order := unsafeslice.NativeByteOrder()
+	if got := order.Uint32(b); got != u[0] {
+		t.Errorf("%v.Uint32(%x) = %#x; want %#x", order, b, got, u[0])
+	}
+	if little := unsafeslice.NativeIsLittleEndian(); little != (b[0] == 0x04) {
+		t.Errorf("NativeIsLittleEndian() = %v, but uint32 %#x is stored as %x", little, u[0], b)
+	}
+}
+
+func TestSafetyChecksEnabled(t *testing.T) {
+	if got, want := unsafeslice.SafetyChecksEnabled(), safetyChecksEnabled; got != want {
+		t.Errorf("SafetyChecksEnabled() = %v; want %v", got, want)
+	}
+}
+
+func TestChecksumString(t *testing.T) {
+	a := unsafeslice.ChecksumString("Hello, world!")
+	b := unsafeslice.ChecksumString(string([]byte("Hello, world!")))
+	if a != b {
+		t.Errorf("ChecksumString returned %x and %x for equal strings", a, b)
+	}
+	if c := unsafeslice.ChecksumString("Hello, world?"); c == a {
+		t.Errorf("ChecksumString returned %x for different strings", a)
+	}
+}
+
+func TestInternString(t *testing.T) {
+	a := unsafeslice.InternString([]byte("Hello, world!"))
+	b := unsafeslice.InternString([]byte("Hello, world!"))
+	if a != "Hello, world!" || b != a {
+		t.Errorf("InternString returned %q and %q; want %q", a, b, "Hello, world!")
+	}
+	aData, _ := unsafeslice.StringHeader(a)
+	if bData, _ := unsafeslice.StringHeader(b); bData != aData {
+		t.Errorf("InternString returned strings at %#x and %#x for equal contents; want the same backing array", aData, bData)
+	}
+
+	if c := unsafeslice.InternString([]byte("Hello, world?")); c != "Hello, world?" {
+		t.Errorf("InternString([]byte(%q)) = %q", "Hello, world?", c)
+	}
+
+	buf := []byte("Hello, world!")
+	avg := testing.AllocsPerRun(100, func() {
+		unsafeslice.InternString(buf)
+	})
+	if avg > 0 {
+		t.Errorf("InternString of an interned string made %v allocations; want 0", avg)
+	}
+}
+
 func TestStringAllocs(t *testing.T) {
+	if raceEnabled {
+		// The goroutine started by each check makes a varying number of
+		// allocations.
+		t.Skip("allocation counts are not stable under the race detector")
+	}
+
 	t.Run("OfString", func(t *testing.T) {
 		s := "Hello, world!"
 
@@ -189,6 +683,30 @@ func TestStringAllocs(t *testing.T) {
 	})
 }
 
+func TestUncheckedAllocs(t *testing.T) {
+	s := "Hello, world!"
+	var b []byte
+	avg := testing.AllocsPerRun(1000, func() {
+		b = unsafeslice.OfStringUnchecked(s)
+	})
+	if avg > 0 {
+		t.Errorf("unsafeslice.OfStringUnchecked made %v allocations; want 0", avg)
+	}
+	if string(b) != s {
+		t.Errorf("OfStringUnchecked(%q) = %q", s, b)
+	}
+
+	avg = testing.AllocsPerRun(1000, func() {
+		s = unsafeslice.AsStringUnchecked(b)
+	})
+	if avg > 0 {
+		t.Errorf("unsafeslice.AsStringUnchecked made %v allocations; want 0", avg)
+	}
+	if s != string(b) {
+		t.Errorf("AsStringUnchecked(%q) = %q", b, s)
+	}
+}
+
 func BenchmarkOfString(b *testing.B) {
 	in := "Hello, world!"
 	var out []byte